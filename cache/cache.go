@@ -4,7 +4,8 @@ var (
 	// Network is the cache handler instance for http requests. It is indexed
 	// by the URL of the request.
 	Network = newHandler("network")
-	// Package is the cache handler instance for package files. It is indexed
-	// by the package ID.
-	Package = newHandler("package")
+	// Package is the content-addressed store of package files, shared by all
+	// servers of the user. It is indexed by the SHA-512 of the files, and by
+	// the package ids they were installed as.
+	Package = newStore("package")
 )
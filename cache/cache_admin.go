@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"lucy/logger"
+)
+
+// Handler is what every cache exposes for inspection and maintenance, e.g., by
+// `lucy cache`.
+type Handler interface {
+	Name() string
+	Stats() Stats
+	// Entries lists the entries, the first to be evicted first.
+	Entries() []Entry
+	// Verify re-hashes every entry and drops those that do not match.
+	Verify() (checked int, dropped []Entry)
+	// Prune drops the entries older than age, and then the first to be
+	// evicted until the cache fits in size. Zero disables either limit.
+	Prune(age time.Duration, size int64) (dropped []Entry)
+	ClearAll() error
+}
+
+// All lists the caches in the order they are shown.
+var All = []Handler{Network, Package}
+
+// Entry is a cache entry as shown to the user. Created and Expiration are only
+// set for the network cache; LastUsed and Ids only for the package store.
+type Entry struct {
+	Cache      string    `json:"cache"`
+	Key        string    `json:"key"`
+	Filename   string    `json:"filename"`
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	Created    time.Time `json:"created"`
+	Expiration time.Time `json:"expiration"`
+	LastUsed   time.Time `json:"last_used"`
+	Ids        []string  `json:"ids,omitempty"`
+}
+
+type Stats struct {
+	Cache   string `json:"cache"`
+	Dir     string `json:"dir"`
+	Enabled bool   `json:"enabled"`
+	Entries int    `json:"entries"`
+	Size    int64  `json:"size"`
+	MaxSize int64  `json:"max_size"`
+	Expired int    `json:"expired"`
+}
+
+// --- Network -----------------------------------------------------------------
+
+func (h *handler) Name() string { return h.name }
+
+func (h *handler) Stats() Stats {
+	defer h.lock(false)()
+	stats := Stats{Cache: h.name, Dir: h.dir, Enabled: h.on}
+	if !h.on {
+		return stats
+	}
+	stats.MaxSize = int64(h.manifest.MaxSize)
+	for _, item := range h.manifest.Content {
+		stats.Entries++
+		stats.Size += int64(item.Size)
+		if item.Expiration.Before(time.Now()) {
+			stats.Expired++
+		}
+	}
+	return stats
+}
+
+func (h *handler) Entries() []Entry {
+	defer h.lock(false)()
+	return h.entries()
+}
+
+func (h *handler) entries() []Entry {
+	if !h.on {
+		return nil
+	}
+	entries := make([]Entry, 0, len(h.manifest.Content))
+	for _, item := range h.manifest.Content {
+		entries = append(entries, h.entry(item))
+	}
+	slices.SortFunc(
+		entries,
+		func(a, b Entry) int { return a.Expiration.Compare(b.Expiration) },
+	)
+	return entries
+}
+
+func (h *handler) entry(item cacheItem) Entry {
+	return Entry{
+		Cache:      h.name,
+		Key:        string(item.Key),
+		Filename:   item.Filename,
+		Path:       path.Join(h.dir, item.Sha1, item.Filename),
+		Size:       int64(item.Size),
+		Created:    item.Created,
+		Expiration: item.Expiration,
+	}
+}
+
+// Verify compares every file with the hash in the manifest, which is what Add
+// computed with hash.
+func (h *handler) Verify() (checked int, dropped []Entry) {
+	defer h.lock(true)()
+	if !h.on {
+		return 0, nil
+	}
+	for _, item := range h.manifest.Content {
+		checked++
+		entry := h.entry(item)
+		data, err := os.ReadFile(entry.Path)
+		if err == nil && hash(data) == item.Sha1 {
+			continue
+		}
+		logger.Info("dropping corrupted cache item " + entry.Key)
+		if err := h.remove(item.Key); err != nil {
+			logger.Warn(err)
+			continue
+		}
+		dropped = append(dropped, entry)
+	}
+	return checked, dropped
+}
+
+// Prune of the network cache always drops the expired entries. Entries made
+// before creation times were recorded count as old.
+func (h *handler) Prune(age time.Duration, size int64) (dropped []Entry) {
+	defer h.lock(true)()
+	if !h.on {
+		return nil
+	}
+	var total int64
+	for _, entry := range h.entries() {
+		old := entry.Expiration.Before(time.Now()) ||
+			(age > 0 && entry.Created.Before(time.Now().Add(-age)))
+		if !old {
+			total += entry.Size
+			continue
+		}
+		if err := h.remove(key(entry.Key)); err != nil {
+			logger.Warn(err)
+			continue
+		}
+		dropped = append(dropped, entry)
+	}
+	for _, entry := range h.entries() {
+		if size <= 0 || total <= size {
+			break
+		}
+		if err := h.remove(key(entry.Key)); err != nil {
+			logger.Warn(err)
+			continue
+		}
+		total -= entry.Size
+		dropped = append(dropped, entry)
+	}
+	return dropped
+}
+
+// --- Package -----------------------------------------------------------------
+
+func (s *store) Name() string { return s.name }
+
+func (s *store) Stats() Stats {
+	defer s.lock(false)()
+	stats := Stats{Cache: s.name, Dir: s.dir, Enabled: s.on}
+	if !s.on {
+		return stats
+	}
+	stats.MaxSize = s.index.MaxSize
+	for _, obj := range s.index.Objects {
+		stats.Entries++
+		stats.Size += obj.Size
+	}
+	return stats
+}
+
+func (s *store) Entries() []Entry {
+	defer s.lock(false)()
+	return s.entries()
+}
+
+func (s *store) entries() []Entry {
+	if !s.on {
+		return nil
+	}
+	entries := make([]Entry, 0, len(s.index.Objects))
+	for sum, obj := range s.index.Objects {
+		entries = append(
+			entries, Entry{
+				Cache:    s.name,
+				Key:      sum,
+				Filename: obj.Filename,
+				Path:     s.objectPath(sum),
+				Size:     obj.Size,
+				LastUsed: obj.LastUsed,
+				Ids:      slices.Clone(obj.Ids),
+			},
+		)
+	}
+	slices.SortFunc(
+		entries,
+		func(a, b Entry) int { return a.LastUsed.Compare(b.LastUsed) },
+	)
+	return entries
+}
+
+func (s *store) Verify() (checked int, dropped []Entry) {
+	defer s.lock(true)()
+	for _, entry := range s.entries() {
+		checked++
+		sum, _, err := sha512File(entry.Path)
+		if err == nil && sum == entry.Key {
+			continue
+		}
+		logger.Info("dropping corrupted package " + entry.Filename)
+		s.drop(entry.Key)
+		dropped = append(dropped, entry)
+	}
+	if len(dropped) != 0 {
+		s.save()
+	}
+	return checked, dropped
+}
+
+// Prune of the store measures the age since an object was last used.
+func (s *store) Prune(age time.Duration, size int64) (dropped []Entry) {
+	defer s.lock(true)()
+	var total int64
+	for _, entry := range s.entries() {
+		total += entry.Size
+	}
+	for _, entry := range s.entries() {
+		old := age > 0 && entry.LastUsed.Before(time.Now().Add(-age))
+		over := size > 0 && total > size
+		if !old && !over {
+			break
+		}
+		s.drop(entry.Key)
+		total -= entry.Size
+		dropped = append(dropped, entry)
+	}
+	if len(dropped) != 0 {
+		s.save()
+	}
+	return dropped
+}
+
+// ClearAll removes every object and starts a new index.
+func (s *store) ClearAll() error {
+	defer s.lock(true)()
+	if !s.on {
+		return nil
+	}
+	objects := path.Join(s.dir, storeObjectsDir)
+	if err := os.RemoveAll(objects); err != nil {
+		return fmt.Errorf("failed to clear package store: %w", err)
+	}
+	if err := os.MkdirAll(objects, os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Remove(s.indexPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	maxSize := s.index.MaxSize
+	s.index = readStoreIndex(s.indexPath)
+	s.index.MaxSize = maxSize
+	return nil
+}
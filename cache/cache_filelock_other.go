@@ -0,0 +1,31 @@
+//go:build !windows && !unix && !linux && !darwin
+
+/*
+Copyright 2024 4rcadia
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "errors"
+
+// fileLock is not available on this platform, only the in-process lock of
+// each cache applies.
+type fileLock struct{}
+
+func lockFile(name string, exclusive bool) (*fileLock, error) {
+	return nil, errors.New("file locking is not supported on this platform")
+}
+
+func (l *fileLock) unlock() {}
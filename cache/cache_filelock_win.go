@@ -0,0 +1,67 @@
+//go:build windows
+
+/*
+Copyright 2024 4rcadia
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// fileLock is an advisory lock on a file, shared by all lucy processes. It
+// does not stop other programs from touching the cache.
+type fileLock struct {
+	file *os.File
+}
+
+// lockFile blocks until the lock is acquired.
+func lockFile(name string, exclusive bool) (*fileLock, error) {
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	var flags uint32
+	if exclusive {
+		flags = windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	err = windows.LockFileEx(
+		windows.Handle(file.Fd()),
+		flags,
+		0,
+		1,
+		0,
+		&windows.Overlapped{},
+	)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &fileLock{file: file}, nil
+}
+
+func (l *fileLock) unlock() {
+	_ = windows.UnlockFileEx(
+		windows.Handle(l.file.Fd()),
+		0,
+		1,
+		0,
+		&windows.Overlapped{},
+	)
+	_ = l.file.Close()
+}
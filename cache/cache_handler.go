@@ -1,9 +1,12 @@
 package cache
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
+	"sync"
 	"time"
 
 	"lucy/global"
@@ -19,14 +22,25 @@ const (
 )
 
 type handler struct {
+	// mu guards the manifest, since downloads may run concurrently. Other lucy
+	// processes are kept out by the lock file, see lock.
+	mu           sync.Mutex
+	name         string
 	on           bool
 	dir          string
 	manifest     *manifest
 	manifestPath string
+	lockPath     string
+	// loaded is the hash of the manifest on disk when it was last read or
+	// written, so that it is only parsed again once its content changed. The
+	// modification time is not trusted for that, a rewrite of the same size
+	// may keep it within its granularity.
+	loaded [sha256.Size]byte
 }
 
 func newHandler(name string) (obj *handler) {
 	obj = &handler{
+		name:     name,
 		on:       true,
 		dir:      setDir(name),
 		manifest: nil,
@@ -42,6 +56,10 @@ func newHandler(name string) (obj *handler) {
 	}
 
 	obj.manifestPath = path.Join(obj.dir, manifestFilename)
+	obj.lockPath = path.Join(obj.dir, lockFilename)
+	if obj.on {
+		defer obj.lock(true)()
+	}
 	obj.manifest = readManifest(obj.manifestPath)
 	if obj.dir == "" || obj.manifest == nil || obj.manifest.Content == nil {
 		obj.on = false
@@ -77,23 +95,68 @@ func (h *handler) Add(
 	filename string,
 	k string,
 	expiration time.Duration,
+) (err error) {
+	defer h.lock(true)()
+	return h.add(
+		data,
+		filename,
+		k,
+		time.Now().Add(tools.Ternary(expiration == 0, defaultLifeTime, expiration)),
+		Meta{},
+	)
+}
+
+// Meta holds the validators of an http response, with which a stale entry can
+// be revalidated rather than fetched again.
+type Meta struct {
+	ETag         string
+	LastModified string
+}
+
+func (m Meta) revalidatable() bool {
+	return m.ETag != "" || m.LastModified != ""
+}
+
+// AddWithMeta is Add for http responses, with an expiration time given by the
+// server and the validators of the response. A zero expiration time applies the
+// default lifetime.
+func (h *handler) AddWithMeta(
+	data []byte,
+	filename string,
+	k string,
+	expires time.Time,
+	meta Meta,
+) (err error) {
+	defer h.lock(true)()
+	return h.add(data, filename, k, expires, meta)
+}
+
+func (h *handler) add(
+	data []byte,
+	filename string,
+	k string,
+	expires time.Time,
+	meta Meta,
 ) (err error) {
 	if !h.on {
 		return nil
 	}
+	if expires.IsZero() {
+		expires = time.Now().Add(defaultLifeTime)
+	}
 	key := key(k)
 	hash := hash(data)
 	if filename == "" {
 		filename = hash
 	}
 
-	if h.Exist(k) {
+	if h.exist(k) {
 		// update the cache item if it exists
 		if h.manifest.Content[key].Sha1 != hash {
-			_ = h.Remove(key)
+			_ = h.remove(key)
 		} else {
-			// same hash, no need to update
-			return nil
+			// same hash, only the lifetime and the validators are renewed
+			return h.renew(key, expires, meta)
 		}
 	}
 
@@ -112,15 +175,14 @@ func (h *handler) Add(
 
 	// update the manifest
 	h.manifest.Content[key] = cacheItem{
-		Filename: filename,
-		Size:     len(data),
-		Sha1:     hash,
-		Expiration: tools.Ternary(
-			expiration == 0,
-			time.Now().Add(defaultLifeTime),
-			time.Now().Add(expiration),
-		),
-		Key: key,
+		Filename:     filename,
+		Size:         len(data),
+		Sha1:         hash,
+		Created:      time.Now(),
+		Expiration:   expires,
+		ETag:         meta.ETag,
+		LastModified: meta.LastModified,
+		Key:          key,
 	}
 
 	// update manifest file
@@ -136,7 +198,49 @@ func (h *handler) Add(
 	return nil
 }
 
+// Lookup tells whether the entry exists, and whether it is still fresh. A stale
+// entry is still served by Get, so that it can be used after the server
+// confirms it with the returned validators.
+func (h *handler) Lookup(k string) (meta Meta, fresh bool, ok bool) {
+	defer h.lock(false)()
+	if !h.exist(k) {
+		return Meta{}, false, false
+	}
+	item := h.manifest.Content[key(k)]
+	meta = Meta{ETag: item.ETag, LastModified: item.LastModified}
+	return meta, time.Now().Before(item.Expiration), true
+}
+
+// Renew extends the lifetime of an entry confirmed by the server, e.g., with a
+// 304 Not Modified. Validators sent along replace the stored ones. A zero
+// expiration time applies the default lifetime.
+func (h *handler) Renew(k string, expires time.Time, meta Meta) error {
+	defer h.lock(true)()
+	if !h.exist(k) {
+		return nil
+	}
+	return h.renew(key(k), expires, meta)
+}
+
+func (h *handler) renew(key key, expires time.Time, meta Meta) error {
+	if expires.IsZero() {
+		expires = time.Now().Add(defaultLifeTime)
+	}
+	item := h.manifest.Content[key]
+	item.Expiration = expires
+	if meta.revalidatable() {
+		item.ETag, item.LastModified = meta.ETag, meta.LastModified
+	}
+	h.manifest.Content[key] = item
+	return updateManifest(h.manifestPath, h.manifest)
+}
+
 func (h *handler) Exist(k string) bool {
+	defer h.lock(false)()
+	return h.exist(k)
+}
+
+func (h *handler) exist(k string) bool {
 	if !h.on {
 		return false
 	}
@@ -145,6 +249,7 @@ func (h *handler) Exist(k string) bool {
 }
 
 func (h *handler) Get(k string) (hit bool, file *os.File, err error) {
+	defer h.lock(false)()
 	if !h.on {
 		return false, nil, nil
 	}
@@ -164,6 +269,11 @@ func (h *handler) Get(k string) (hit bool, file *os.File, err error) {
 }
 
 func (h *handler) Remove(key key) (err error) {
+	defer h.lock(true)()
+	return h.remove(key)
+}
+
+func (h *handler) remove(key key) (err error) {
 	if !h.on {
 		return nil
 	}
@@ -195,6 +305,7 @@ func (h *handler) Remove(key key) (err error) {
 //
 // This is useful when the cache is corrupted or when you want to start fresh.
 func (h *handler) ClearAll() error {
+	defer h.lock(true)()
 	if !h.on {
 		return nil
 	}
@@ -217,3 +328,73 @@ func (h *handler) ClearAll() error {
 
 	return nil
 }
+
+// SetMaxSize changes the size limit in bytes, evicting the items expiring
+// soonest if the cache no longer fits.
+func (h *handler) SetMaxSize(size int64) {
+	defer h.lock(true)()
+	if !h.on || size <= 0 || int64(h.manifest.MaxSize) == size {
+		return
+	}
+	h.manifest.MaxSize = int(size)
+	h.maintainCacheLimit()
+	if err := updateManifest(h.manifestPath, h.manifest); err != nil {
+		logger.Warn(
+			fmt.Errorf(
+				"failed to update manifest after resizing: %w",
+				err,
+			),
+		)
+	}
+}
+
+// lock takes the in-process lock and then the lock file, which is shared by all
+// lucy processes, and re-reads the manifest that another process may have
+// changed since. Readers take a shared lock, so an eviction never removes a file
+// between a reader finding it in the manifest and opening it.
+//
+// The returned function releases both. Without a lock file, e.g., on a
+// read-only filesystem, only the in-process lock applies.
+func (h *handler) lock(exclusive bool) (unlock func()) {
+	h.mu.Lock()
+	fl, err := lockFile(h.lockPath, exclusive)
+	if err != nil {
+		logger.Debug("cannot lock " + h.lockPath + ": " + err.Error())
+	}
+	if h.on && h.manifest != nil {
+		h.reload()
+	}
+	return func() {
+		// Whatever is on disk now was written under this lock, and so is
+		// already in memory
+		if exclusive && h.on {
+			if data, err := os.ReadFile(h.manifestPath); err == nil {
+				h.loaded = sha256.Sum256(data)
+			}
+		}
+		if fl != nil {
+			fl.unlock()
+		}
+		h.mu.Unlock()
+	}
+}
+
+// reload replaces the manifest in memory with the one on disk. A manifest that
+// cannot be read is left to readManifest to recover from, so the one in memory
+// is kept. The manifest is not parsed again unless its content changed since.
+func (h *handler) reload() {
+	data, err := os.ReadFile(h.manifestPath)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	if sum == h.loaded {
+		return
+	}
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil || m.Content == nil {
+		return
+	}
+	h.manifest = m
+	h.loaded = sum
+}
@@ -15,14 +15,20 @@ import (
 
 const (
 	manifestFilename = "cache.json"
+	lockFilename     = ".lock"
 )
 
 type cacheItem struct {
 	Filename   string    `json:"filename"`
 	Size       int       `json:"size"`
 	Sha1       string    `json:"sha1"`
+	Created    time.Time `json:"created"`
 	Expiration time.Time `json:"expiration"`
 	Key        key       `json:"key"`
+
+	// Validators of http responses, see Meta
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
 }
 
 type key string
@@ -170,8 +176,9 @@ func resetCache(manifestPath string) error {
 
 	// clear all cache
 	for _, entry := range entries {
-		// just for safety, skip the manifest file
-		if entry.Name() == manifestFilename {
+		// just for safety, skip the manifest file, and the lock file which
+		// other processes may be waiting on
+		if entry.Name() == manifestFilename || entry.Name() == lockFilename {
 			continue
 		}
 
@@ -0,0 +1,10 @@
+//go:build darwin
+
+package cache
+
+import "golang.org/x/sys/unix"
+
+// reflink clones src to dest with clonefile, which is supported by APFS.
+func reflink(src string, dest string) error {
+	return unix.Clonefile(src, dest, unix.CLONE_NOFOLLOW)
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package cache
+
+import "errors"
+
+func reflink(src string, dest string) error {
+	return errors.New("reflink is not supported on this platform")
+}
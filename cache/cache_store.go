@@ -0,0 +1,371 @@
+package cache
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"lucy/logger"
+	"lucy/tools"
+)
+
+const (
+	storeIndexFilename = "store.json"
+	storeObjectsDir    = "objects"
+	defaultStoreSize   = 2 * 1000 * 1000 * 1000 // 2GB
+)
+
+var ErrorNotInStore = errors.New("not in package store")
+
+// store keeps package files by their SHA-512, so that a file shared by several
+// servers is downloaded and stored once per machine. Objects are also indexed
+// by the package ids they were installed as.
+//
+// Files are placed into servers by LinkOut, which prefers a hardlink, then a
+// reflink, and copies as a last resort. A hardlinked file in a server shares
+// its data with the store, so evicting the object from the store never breaks
+// the server.
+type store struct {
+	mu        sync.Mutex
+	name      string
+	on        bool
+	dir       string
+	index     *storeIndex
+	indexPath string
+	lockPath  string
+}
+
+type storeIndex struct {
+	MaxSize int64                  `json:"max_size"`
+	Objects map[string]storeObject `json:"objects"` // by sha512
+	Ids     map[string]string      `json:"ids"`     // package id to sha512
+}
+
+type storeObject struct {
+	Filename string    `json:"filename"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"`
+	Ids      []string  `json:"ids"`
+}
+
+func newStore(name string) (obj *store) {
+	obj = &store{name: name, on: true, dir: setDir(name)}
+	obj.indexPath = path.Join(obj.dir, storeIndexFilename)
+	obj.lockPath = path.Join(obj.dir, lockFilename)
+	if err := os.MkdirAll(path.Join(obj.dir, storeObjectsDir), os.ModePerm); err != nil {
+		logger.Warn(
+			fmt.Errorf(
+				"cannnot create store directory, disabling %s store: %w",
+				name, err,
+			),
+		)
+		obj.on = false
+		return obj
+	}
+	defer obj.lock(false)()
+	obj.index = readStoreIndex(obj.indexPath)
+	return obj
+}
+
+func readStoreIndex(filepath string) *storeIndex {
+	index := &storeIndex{
+		MaxSize: defaultStoreSize,
+		Objects: make(map[string]storeObject),
+		Ids:     make(map[string]string),
+	}
+	data, err := os.ReadFile(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return index
+	} else if err != nil {
+		logger.Warn(fmt.Errorf("cannot read store index, starting over: %w", err))
+		return index
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		logger.Warn(fmt.Errorf("malformed store index, starting over: %w", err))
+		return &storeIndex{
+			MaxSize: defaultStoreSize,
+			Objects: make(map[string]storeObject),
+			Ids:     make(map[string]string),
+		}
+	}
+	if index.Objects == nil {
+		index.Objects = make(map[string]storeObject)
+	}
+	if index.Ids == nil {
+		index.Ids = make(map[string]string)
+	}
+	return index
+}
+
+// save writes the index through a temporary file, so that a process reading
+// without the lock, e.g., an older lucy, never sees a partial index.
+func (s *store) save() {
+	data, err := json.Marshal(s.index)
+	if err == nil {
+		temp := s.indexPath + ".tmp"
+		if err = os.WriteFile(temp, data, 0o644); err == nil {
+			err = os.Rename(temp, s.indexPath)
+		}
+	}
+	if err != nil {
+		logger.Warn(fmt.Errorf("failed to update store index: %w", err))
+	}
+}
+
+// lock is the same as handler.lock, re-reading the index instead.
+func (s *store) lock(exclusive bool) (unlock func()) {
+	s.mu.Lock()
+	fl, err := lockFile(s.lockPath, exclusive)
+	if err != nil {
+		logger.Debug("cannot lock " + s.lockPath + ": " + err.Error())
+	}
+	if s.on && s.index != nil {
+		maxSize := s.index.MaxSize
+		s.index = readStoreIndex(s.indexPath)
+		// The quota comes from the config of this process
+		s.index.MaxSize = maxSize
+	}
+	return func() {
+		if fl != nil {
+			fl.unlock()
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *store) objectPath(sum string) string {
+	return path.Join(s.dir, storeObjectsDir, sum[:2], sum)
+}
+
+// Put adds the file to the store under the package id, and returns its
+// SHA-512. The file itself is left in place; if it is on the same filesystem
+// as the store, both share the data through a hardlink.
+func (s *store) Put(file string, id string) (sum string, err error) {
+	sum, size, err := sha512File(file)
+	if err != nil {
+		return "", err
+	}
+
+	defer s.lock(true)()
+	if !s.on {
+		return sum, nil
+	}
+
+	obj, ok := s.index.Objects[sum]
+	if !ok || !s.intact(sum, obj) {
+		dest := s.objectPath(sum)
+		if err := os.MkdirAll(path.Dir(dest), os.ModePerm); err != nil {
+			return "", err
+		}
+		_ = os.Remove(dest)
+		if _, err := linkFile(file, dest); err != nil {
+			return "", err
+		}
+		obj = storeObject{Filename: path.Base(file), Size: size}
+	}
+	obj.LastUsed = time.Now()
+	if id != "" {
+		if old, ok := s.index.Ids[id]; ok && old != sum {
+			s.unindex(old, id)
+		}
+		s.index.Ids[id] = sum
+		if !slices.Contains(obj.Ids, id) {
+			obj.Ids = append(obj.Ids, id)
+		}
+	}
+	s.index.Objects[sum] = obj
+	s.evict(sum)
+	s.save()
+	return sum, nil
+}
+
+// Has tells whether an intact object of the SHA-512 is in the store.
+func (s *store) Has(sum string) bool {
+	defer s.lock(false)()
+	if !s.on {
+		return false
+	}
+	obj, ok := s.index.Objects[sum]
+	return ok && s.intact(sum, obj)
+}
+
+// Lookup returns the SHA-512 of the object stored for the package id.
+func (s *store) Lookup(id string) (sum string, ok bool) {
+	defer s.lock(false)()
+	if !s.on {
+		return "", false
+	}
+	sum, ok = s.index.Ids[id]
+	return sum, ok
+}
+
+// LinkOut places the object at dest, and returns how it was placed: "hardlink",
+// "reflink" or "copy". The object is checked by its size only, a full check is
+// left to Verify.
+func (s *store) LinkOut(sum string, dest string) (method string, err error) {
+	defer s.lock(true)()
+	if !s.on {
+		return "", ErrorNotInStore
+	}
+	obj, ok := s.index.Objects[sum]
+	if !ok {
+		return "", ErrorNotInStore
+	}
+	if !s.intact(sum, obj) {
+		s.drop(sum)
+		s.save()
+		return "", ErrorNotInStore
+	}
+	method, err = linkFile(s.objectPath(sum), dest)
+	if err != nil {
+		return "", err
+	}
+	obj.LastUsed = time.Now()
+	s.index.Objects[sum] = obj
+	s.save()
+	return method, nil
+}
+
+// Remove deletes an object and its id indexes.
+func (s *store) Remove(sum string) {
+	defer s.lock(true)()
+	if !s.on {
+		return
+	}
+	s.drop(sum)
+	s.save()
+}
+
+// SetMaxSize changes the quota in bytes, evicting the least recently used
+// objects if the store no longer fits.
+func (s *store) SetMaxSize(size int64) {
+	defer s.lock(true)()
+	if !s.on || size <= 0 || s.index.MaxSize == size {
+		return
+	}
+	s.index.MaxSize = size
+	s.evict("")
+	s.save()
+}
+
+// evict removes the least recently used objects until the store fits in its
+// quota. The object of keep is never removed, so that a file larger than the
+// quota can still be installed.
+func (s *store) evict(keep string) {
+	var total int64
+	for _, obj := range s.index.Objects {
+		total += obj.Size
+	}
+	if total <= s.index.MaxSize {
+		return
+	}
+	sums := make([]string, 0, len(s.index.Objects))
+	for sum := range s.index.Objects {
+		sums = append(sums, sum)
+	}
+	slices.SortFunc(
+		sums,
+		func(a, b string) int {
+			return s.index.Objects[a].LastUsed.Compare(s.index.Objects[b].LastUsed)
+		},
+	)
+	for _, sum := range sums {
+		if total <= s.index.MaxSize {
+			break
+		}
+		if sum == keep {
+			continue
+		}
+		logger.Info("evicting " + s.index.Objects[sum].Filename + " from package store")
+		total -= s.index.Objects[sum].Size
+		s.drop(sum)
+	}
+}
+
+func (s *store) drop(sum string) {
+	obj, ok := s.index.Objects[sum]
+	if !ok {
+		return
+	}
+	if err := os.Remove(s.objectPath(sum)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn(fmt.Errorf("failed to remove %s from package store: %w", obj.Filename, err))
+	}
+	for _, id := range obj.Ids {
+		if s.index.Ids[id] == sum {
+			delete(s.index.Ids, id)
+		}
+	}
+	delete(s.index.Objects, sum)
+}
+
+// unindex detaches the id from the object it pointed to.
+func (s *store) unindex(sum string, id string) {
+	obj, ok := s.index.Objects[sum]
+	if !ok {
+		return
+	}
+	obj.Ids = slices.DeleteFunc(obj.Ids, func(i string) bool { return i == id })
+	s.index.Objects[sum] = obj
+}
+
+// intact checks the object file by its size.
+func (s *store) intact(sum string, obj storeObject) bool {
+	stat, err := os.Stat(s.objectPath(sum))
+	return err == nil && stat.Size() == obj.Size
+}
+
+func sha512File(file string) (sum string, size int64, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", 0, err
+	}
+	defer tools.CloseReader(f, logger.Warn)
+	h := sha512.New()
+	size, err = io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// linkFile makes dest have the content of src as cheaply as the filesystem
+// allows: a hardlink, then a reflink, then a plain copy.
+func linkFile(src string, dest string) (method string, err error) {
+	if err = os.Link(src, dest); err == nil {
+		return "hardlink", nil
+	}
+	if err = reflink(src, dest); err == nil {
+		return "reflink", nil
+	}
+	logger.Debug("cannot link " + src + ", copying: " + err.Error())
+	if err = copyFile(src, dest); err != nil {
+		return "", err
+	}
+	return "copy", nil
+}
+
+func copyFile(src string, dest string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer tools.CloseReader(in, logger.Warn)
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dest)
+		return err
+	}
+	return out.Close()
+}
@@ -22,11 +22,19 @@ func setDir(name string) string {
 	return path.Join(dir, global.ProgramName, name)
 }
 
+// staleRetention is how long an expired entry that can be revalidated is kept.
+// Revalidating it costs a request, but not the transfer.
+const staleRetention = 7 * 24 * time.Hour
+
 func (h *handler) clearExpiredCache() {
 	for _, item := range h.manifest.Content {
-		if item.Expiration.Before(time.Now()) {
+		expiration := item.Expiration
+		if (Meta{ETag: item.ETag, LastModified: item.LastModified}).revalidatable() {
+			expiration = expiration.Add(staleRetention)
+		}
+		if expiration.Before(time.Now()) {
 			logger.Info("removing expired cache item " + item.Key)
-			err := h.Remove(item.Key)
+			err := h.remove(item.Key)
 			if err != nil {
 				continue
 			}
@@ -50,7 +58,7 @@ func (h *handler) maintainCacheLimit() {
 			break
 		}
 		logger.Info("removing cache item " + item.Key)
-		err := h.Remove(item.Key)
+		err := h.remove(item.Key)
 		if err != nil {
 			continue
 		}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 
+	"lucy/global"
+	"lucy/probe"
 	"lucy/tools"
+	"lucy/util"
 
 	"github.com/urfave/cli/v3"
 )
@@ -15,10 +18,16 @@ var Frontend = "cli"
 
 // Each subcommand (and its action function) should be in its own file
 
+func init() {
+	// Respect the proxies of MCDR, unless lucy is told otherwise
+	util.SetProxyFallback(probe.McdrProxies)
+}
+
 // Cli is the main command for lucy
 var Cli = &cli.Command{
-	Name:  "lucy",
-	Usage: "The Minecraft server-side package manager",
+	Name:    "lucy",
+	Usage:   "The Minecraft server-side package manager",
+	Version: global.Version,
 	Action: tools.Decorate(
 		actionEmpty,
 		decoratorBaseCommandFlags,
@@ -43,6 +52,14 @@ var Cli = &cli.Command{
 			Usage: "Show debug logs",
 			Value: false,
 		},
+		&cli.StringFlag{
+			Name:  "proxy",
+			Usage: "Send requests through `PROXY` (http, https or socks5)",
+		},
+		&cli.BoolFlag{
+			Name:  "offline",
+			Usage: "Use only cached metadata and the package store, never the network",
+		},
 		&cli.BoolFlag{
 			Name:   "dump-logs",
 			Usage:  "Dump the log history to console before exit",
@@ -57,6 +74,11 @@ var Cli = &cli.Command{
 		subcmdSearch,
 		subcmdAdd,
 		subcmdInit,
+		subcmdConfig,
+		subcmdCache,
+		subcmdPrefetch,
+		subcmdTree,
+		subcmdWhy,
 	},
 	EnableShellCompletion:  true,
 	Suggest:                true,
@@ -4,23 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 
+	"lucy/install"
 	"lucy/probe"
 	"lucy/remote"
 	"lucy/remote/source"
 	"lucy/tools"
-	"lucy/util"
 
 	"lucy/logger"
-	"lucy/syntax"
 	"lucy/types"
 
 	"github.com/urfave/cli/v3"
 )
 
 var subcmdAdd = &cli.Command{
-	Name:  "add",
-	Usage: "Add new mods, plugins, or server modules",
+	Name:      "add",
+	Usage:     "Add new mods, plugins, or server modules",
+	ArgsUsage: "PACKAGE|FILE|URL...",
+	Description: "A package can also be a local .jar, .zip, .pyz or .mcdr file, or " +
+		"a URL to one. Such packages are not tracked by any source, and " +
+		"are never updated by lucy.",
 	Flags: []cli.Flag{
 		&cli.BoolFlag{
 			Name:    "force",
@@ -34,6 +38,7 @@ var subcmdAdd = &cli.Command{
 			Usage:   "Specify the source to download from (modrinth, mcdr)",
 			Value:   "none",
 		},
+		flagFrom,
 		flagNoStyle,
 	},
 	Action: tools.Decorate(
@@ -47,8 +52,21 @@ var actionAdd cli.ActionFunc = func(
 	ctx context.Context,
 	cmd *cli.Command,
 ) error {
-	// get id from args
-	id := syntax.Parse(cmd.Args().First())
+	var idArgs, fileArgs []string
+	for _, arg := range cmd.Args().Slice() {
+		if isFileArg(arg) {
+			fileArgs = append(fileArgs, arg)
+		} else {
+			idArgs = append(idArgs, arg)
+		}
+	}
+	var ids []types.PackageId
+	if len(idArgs) != 0 || cmd.String(flagFromName) != "" {
+		var err error
+		if ids, err = parseIds(cmd, idArgs); err != nil {
+			return err
+		}
+	}
 
 	// probe server info
 	serverInfo := probe.ServerInfo()
@@ -63,101 +81,184 @@ var actionAdd cli.ActionFunc = func(
 		return errors.New("no executable found, `lucy add` requires a server in current directory")
 	}
 
-	// check if the specified platform matches the server platform
-	if id.Platform != types.AnyPlatform {
-		if id.Platform == types.Mcdr {
-			// for mcdr, we only need to check if it's mcdr-managed
-			if serverInfo.Environments.Mcdr == nil {
-				return errors.New("mcdr not found")
+	// Every package is looked up before any is installed, so that a typo does
+	// not leave the server half done
+	var pkgs []packageHit
+	var errs []error
+	for _, id := range ids {
+		hit, err := lookUpForAdd(ctx, cmd, id, serverInfo, len(ids) == 1)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id.String(), err))
+			continue
+		}
+		if hit != nil {
+			pkgs = append(pkgs, *hit)
+		}
+	}
+	var files []localFile
+	if len(fileArgs) != 0 {
+		tempDir, err := os.MkdirTemp("", "lucy-add-")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+		found, err := fetchFiles(fileArgs, tempDir)
+		if err != nil {
+			return err
+		}
+		for i, arg := range fileArgs {
+			if err := found[i].Err; err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", arg, err))
+				continue
 			}
-		} else if id.Platform != serverInfo.Executable.ModLoader {
-			return errors.New("platform mismatch")
-		}
-	}
-
-	// Get the appropriate directory to download file to.
-	// This is a temporary solution. Installation is not supposed to be this simple.
-	// The installer should be designed as an injectable interface to allow non-standard
-	// installation methods.
-	var dir string
-	switch id.Platform {
-	case types.AnyPlatform:
-		logger.ShowInfo("no platform specified, attempting to infer")
-	case types.Mcdr:
-		dir = serverInfo.Environments.Mcdr.PluginDirectories[0] // TODO: Change this
-	case types.Forge, types.Fabric:
-		// TODO: This is a temporary solution, we should have a more robust way to determine the mod directory
-		dir = serverInfo.ModPath[0]
-	default:
-		return errors.New("unsupported platform")
+			origin := tools.Ternary(isUrl(arg), arg, found[i].Path)
+			f, err := resolveFile(found[i].Path, origin, serverInfo, cmd.Bool("force"))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", arg, err))
+				continue
+			}
+			files = append(files, f)
+		}
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+
+	// TODO: Dependencies are not resolved yet. For sources like modrinth, the
+	// dependency info from remote is not reliable.
+	tx := install.NewTransaction()
+	for _, f := range files {
+		dir, err := install.TargetDir(f.pkg.Id.Platform, serverInfo)
+		if err != nil {
+			return err
+		}
+		tx.AddFile(f.pkg, f.path, f.origin, dir)
+	}
+	for _, hit := range pkgs {
+		dir, err := install.TargetDir(hit.id.Platform, serverInfo)
+		if err != nil {
+			return err
+		}
+		if err := tx.Add(types.Package{Id: hit.id, Remote: &hit.remote}, dir); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("install failed: %w", err)
+	}
+	return nil
+}
+
+// lookUpForAdd finds the package to add. When alone is true and the package
+// is ambiguous, the browser is opened for the user to pick from, and no hit
+// is returned.
+func lookUpForAdd(
+	ctx context.Context,
+	cmd *cli.Command,
+	id types.PackageId,
+	serverInfo types.ServerInfo,
+	alone bool,
+) (*packageHit, error) {
+	if id.Platform != types.AnyPlatform {
+		if err := checkPlatform(id.Platform, serverInfo); err != nil {
+			return nil, err
+		}
 	}
 
-	p := types.Package{
-		Id:           id,
-		Dependencies: nil,
-		Local:        nil,
-		Remote:       nil,
-		Supports:     nil,
-		Information:  nil,
+	candidates, err := sourceCandidates(sourceFlag(cmd), id)
+	if err != nil {
+		return nil, err
 	}
+	hits := findPackage(id, candidates, serverInfo)
 
-	// fetch remote data
-	var remoteData remote.RawPackageRemote
-	var src remote.SourceHandler
-	var err error
+	// A name without a platform is ambiguous when it is found in none or in
+	// several sources. Let the user pick in the browser when we can.
+	if id.Platform == types.AnyPlatform && len(hits) != 1 && alone && interactive() {
+		logger.ShowInfo(
+			tools.Ternary(
+				len(hits) == 0,
+				"package not found, opening the browser",
+				"package found in multiple sources, opening the browser",
+			),
+		)
+		return nil, browse(
+			ctx,
+			id.Name.String(),
+			candidates,
+			types.SearchOptions{IndexBy: types.ByRelevance},
+		)
+	}
+	if len(hits) == 0 {
+		return nil, errors.New("package not found in any source")
+	}
+	if len(hits) > 1 {
+		logger.ShowInfo(id.Name.String() + " found in multiple sources, using " + hits[0].remote.Source.Title())
+	}
+	return &hits[0], nil
+}
 
-	switch cmd.String("source") {
-	case "none":
-		for _, src = range source.All {
-			remoteData, err = src.Fetch(id)
-			if err != nil {
-				logger.ShowInfo(err)
-				err = nil // prevent error got printed twice in the last iteration
-				continue
-			}
-			if remoteData != nil {
-				// found the package, exit loop
-				break
-			}
+// checkPlatform checks if the platform of a package matches the server
+// platform.
+func checkPlatform(platform types.Platform, serverInfo types.ServerInfo) error {
+	if platform == types.Mcdr {
+		// for mcdr, we only need to check if it's mcdr-managed
+		if serverInfo.Environments.Mcdr == nil {
+			return errors.New("mcdr not found")
 		}
+	} else if platform != serverInfo.Executable.ModLoader {
+		return errors.New("platform mismatch")
+	}
+	return nil
+}
+
+// sourceCandidates determines which sources to look the package up from.
+func sourceCandidates(srcName string, id types.PackageId) ([]remote.SourceHandler, error) {
+	switch srcName {
+	case "none", "auto":
+		return prioritized(source.All), nil
 	case source.Mcdr.Name().String():
 		if id.Platform != types.Mcdr && id.Platform != types.AnyPlatform {
-			return fmt.Errorf("source 'mcdr' only supports mcdr platform")
+			return nil, fmt.Errorf("source 'mcdr' only supports mcdr platform")
 		}
-		remoteData, err = source.Mcdr.Fetch(id)
+		return []remote.SourceHandler{source.Mcdr}, nil
 	case source.Modrinth.Name().String():
 		if id.Platform == types.Mcdr {
-			return fmt.Errorf("source 'modrinth' does not support mcdr platform")
+			return nil, fmt.Errorf("source 'modrinth' does not support mcdr platform")
 		}
-		remoteData, err = source.Modrinth.Fetch(id)
+		return []remote.SourceHandler{source.Modrinth}, nil
 	default:
-		return fmt.Errorf("unknown source: %s", cmd.String("source"))
-	}
-	if err != nil {
-		logger.ReportWarn(err)
-	}
-	if remoteData != nil {
-		r := remoteData.ToPackageRemote()
-		p.Remote = &r
-	} else {
-		return errors.New("package not found in any source")
+		return nil, fmt.Errorf("unknown source: %s", srcName)
 	}
+}
 
-	// let's try to get the correct dependency info first
-	// for sources like modrinth, the dependency info from remote is not reliable
-	if id.Platform == types.Mcdr {
-		depsData, err := src.Dependencies(id)
+// packageHit is a package found in a source.
+type packageHit struct {
+	src    remote.SourceHandler
+	id     types.PackageId
+	remote types.PackageRemote
+}
+
+// findPackage looks the package up from every candidate rather than stopping
+// at the first one, so that callers can tell whether the name is ambiguous.
+// Hits are in the order of the candidates.
+func findPackage(
+	id types.PackageId,
+	candidates []remote.SourceHandler,
+	serverInfo types.ServerInfo,
+) (hits []packageHit) {
+	for _, src := range candidates {
+		pid := id
+		if pid.Platform == types.AnyPlatform {
+			pid.Platform = platformOfSource(src.Name(), serverInfo)
+		} else if (pid.Platform == types.Mcdr) != (src.Name() == types.McdrCatalogue) {
+			continue
+		}
+		r, err := remote.Fetch(src, pid)
 		if err != nil {
-			logger.Debug(err)
+			logger.Info(err)
+			continue
 		}
-		tools.PrintAsJson(depsData.ToPackageDependencies())
-		return nil
+		hits = append(hits, packageHit{src: src, id: pid, remote: r})
 	}
-
-	// TODO: util.DownloadFile is a temporary solution
-	_, _, err = util.DownloadFile(p.Remote.FileUrl, dir)
-	if err != nil {
-		logger.ReportError(fmt.Errorf("download failed: %w", err))
-	}
-	return nil
+	return hits
 }
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"lucy/dependency"
+	"lucy/install"
+	"lucy/logger"
+	"lucy/probe"
+	"lucy/types"
+	"lucy/util"
+)
+
+// localFile is a package to add from a local file, or one downloaded from a
+// direct URL.
+type localFile struct {
+	pkg    types.Package
+	path   string
+	origin string // the absolute path of the local file, or the URL
+}
+
+var (
+	errorNotPackage     = errors.New("not a mod or plugin lucy recognizes")
+	errorNotPackageName = errors.New("not named like a .jar, .zip, .pyz or .mcdr file")
+)
+
+// isFileArg tells whether an argument of `lucy add` is a local file or a URL
+// rather than a package id.
+func isFileArg(arg string) bool {
+	return isUrl(arg) || probe.IsPackageFile(arg)
+}
+
+func isUrl(arg string) bool {
+	return strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+}
+
+// fetchFiles finds the file of each argument. URLs are downloaded into
+// tempDir, all at once with a progress display. Results are in the order of
+// the arguments. It only fails when the user interrupted the download.
+func fetchFiles(args []string, tempDir string) ([]util.DownloadResult, error) {
+	results := make([]util.DownloadResult, len(args))
+	var tasks []util.DownloadTask
+	var pending []int // index of the argument of each task
+	for i, arg := range args {
+		if !isUrl(arg) {
+			abs, err := filepath.Abs(arg)
+			if err == nil {
+				_, err = os.Stat(abs)
+			}
+			results[i] = util.DownloadResult{Path: abs, Err: err}
+			continue
+		}
+		// A directory for each, so that files of the same name do not clash
+		dir := filepath.Join(tempDir, strconv.Itoa(i))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			results[i].Err = err
+			continue
+		}
+		tasks = append(tasks, util.DownloadTask{Url: arg, Mirrors: util.MirrorsOf(arg), Dir: dir})
+		pending = append(pending, i)
+	}
+	if len(tasks) == 0 {
+		return results, nil
+	}
+	downloaded, err := install.Download(tasks)
+	if err != nil {
+		return nil, err
+	}
+	for j, r := range downloaded {
+		results[pending[j]] = r
+	}
+	return results, nil
+}
+
+// resolveFile reads the package in the file, which is the local file or was
+// downloaded from the URL origin. The package must be for the server platform,
+// a file holding packages for several loaders gives the one the server runs.
+// Unless forced, unmet dependencies are warned about.
+func resolveFile(
+	path string,
+	origin string,
+	serverInfo types.ServerInfo,
+	force bool,
+) (f localFile, err error) {
+	f.path, f.origin = path, origin
+	// A download is named by the Content-Disposition of the response, or else
+	// the last segment of the URL, either of which may name no package file
+	if !probe.IsPackageFile(f.path) {
+		return f, fmt.Errorf("%w: %s", errorNotPackageName, filepath.Base(f.path))
+	}
+	pkgs := probe.Packages(f.path)
+	if len(pkgs) == 0 {
+		return f, errorNotPackage
+	}
+	var platforms []string
+	for _, p := range pkgs {
+		if checkPlatform(p.Id.Platform, serverInfo) == nil {
+			f.pkg = p
+			break
+		}
+		platforms = append(platforms, p.Id.Platform.Title())
+	}
+	if f.pkg.Id.Name == "" {
+		if !force {
+			return f, fmt.Errorf(
+				"platform mismatch, the file is for %s",
+				strings.Join(platforms, ", "),
+			)
+		}
+		f.pkg = pkgs[0]
+	}
+	f.pkg.Local = nil
+	logger.ShowInfo("adding " + f.pkg.Id.StringFull() + " from " + f.origin)
+
+	if f.pkg.Dependencies != nil && !force {
+		for _, d := range f.pkg.Dependencies.Value {
+			status, installed := dependency.Check(d, serverInfo)
+			switch status {
+			case dependency.StatusMissing:
+				logger.ShowWarn(fmt.Errorf("%s needs %s %s, which is not installed", f.pkg.Id.Name, d.Id.Name, d.Constraint))
+			case dependency.StatusMismatch:
+				logger.ShowWarn(fmt.Errorf("%s needs %s %s, but %s is installed", f.pkg.Id.Name, d.Id.Name, d.Constraint, installed))
+			case dependency.StatusConflict:
+				logger.ShowWarn(fmt.Errorf("%s is incompatible with %s %s, and %s is installed", f.pkg.Id.Name, d.Id.Name, d.Constraint, installed))
+			}
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"lucy/config"
+	"lucy/install"
+	"lucy/logger"
+	"lucy/probe"
+	"lucy/remote"
+	"lucy/remote/source"
+	"lucy/tools"
+	"lucy/tui/browser"
+	"lucy/types"
+)
+
+// browse opens the interactive browser and installs whatever the user queued.
+// It is shared by `lucy search -i` and `lucy add` with an ambiguous name.
+func browse(
+	ctx context.Context,
+	query string,
+	sources []remote.SourceHandler,
+	options types.SearchOptions,
+) error {
+	if !tools.IsTerminal() {
+		return errors.New("interactive mode requires a terminal")
+	}
+
+	serverInfo := probe.ServerInfo()
+	selections, err := browser.Run(query, browserBackend(ctx, sources, options, serverInfo))
+	if err != nil {
+		return err
+	}
+	if len(selections) == 0 {
+		logger.ShowInfo("nothing selected")
+		return nil
+	}
+	return installSelections(selections, serverInfo)
+}
+
+func browserBackend(
+	ctx context.Context,
+	sources []remote.SourceHandler,
+	options types.SearchOptions,
+	serverInfo types.ServerInfo,
+) browser.Backend {
+	return browser.Backend{
+		Search: func(query string) (types.SearchResults, []error, error) {
+			return remote.SearchMultiple(
+				ctx,
+				sources,
+				types.ProjectName(query),
+				options,
+				searchTimeout,
+			)
+		},
+		Details: func(hit types.SearchResult) (types.ProjectInformation, error) {
+			return remote.Information(source.Map[hit.Source], hit.Name)
+		},
+		Versions: func(hit types.SearchResult) ([]types.ProjectVersion, error) {
+			versions, err := remote.Versions(source.Map[hit.Source], hit.Name)
+			if err != nil {
+				return nil, err
+			}
+			return compatibleVersions(versions, serverInfo), nil
+		},
+	}
+}
+
+// compatibleVersions filters out versions that do not support the game version
+// or the mod loader of the server, and prereleases unless the config includes
+// them. Without a known server, only prereleases are filtered.
+func compatibleVersions(
+	versions []types.ProjectVersion,
+	serverInfo types.ServerInfo,
+) (compatible []types.ProjectVersion) {
+	prerelease := config.String(config.KeyPrerelease) == config.PrereleaseInclude
+	exec := serverInfo.Executable
+	for _, v := range versions {
+		if v.Prerelease && !prerelease {
+			continue
+		}
+		if exec == nil || exec == probe.UnknownExecutable {
+			compatible = append(compatible, v)
+			continue
+		}
+		platformOk := len(v.Platforms) == 0 ||
+			slices.ContainsFunc(
+				v.Platforms,
+				func(p types.Platform) bool {
+					return p == types.Mcdr || p.Satisfy(exec.ModLoader)
+				},
+			)
+		gameOk := len(v.GameVersions) == 0 ||
+			slices.Contains(v.GameVersions, exec.GameVersion)
+		if platformOk && gameOk {
+			compatible = append(compatible, v)
+		}
+	}
+	return compatible
+}
+
+// platformOfSource returns the platform that packages from the source install
+// on this server.
+func platformOfSource(src types.Source, serverInfo types.ServerInfo) types.Platform {
+	if src == types.McdrCatalogue {
+		return types.Mcdr
+	}
+	if serverInfo.Executable == nil {
+		return types.AnyPlatform
+	}
+	return serverInfo.Executable.ModLoader
+}
+
+// installSelections resolves every selection and installs them in a single
+// transaction, so that either all of them or none are installed.
+func installSelections(
+	selections []browser.Selection,
+	serverInfo types.ServerInfo,
+) error {
+	if serverInfo.Executable == probe.UnknownExecutable {
+		return errors.New("no executable found, installing requires a server in current directory")
+	}
+
+	tx := install.NewTransaction()
+	for _, s := range selections {
+		src, ok := source.Map[s.Hit.Source]
+		if !ok {
+			return fmt.Errorf("%w: %s", errorUnsupportedSource, s.Hit.Source.Title())
+		}
+		id := types.PackageId{
+			Platform: platformOfSource(s.Hit.Source, serverInfo),
+			Name:     s.Hit.Name,
+			Version:  s.Version,
+		}
+		if err := addToTransaction(tx, id, src, serverInfo); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, s := range selections {
+		logger.ShowInfo("installed " + s.Hit.Name.String())
+	}
+	return nil
+}
+
+func addToTransaction(
+	tx *install.Transaction,
+	id types.PackageId,
+	src remote.SourceHandler,
+	serverInfo types.ServerInfo,
+) error {
+	r, err := remote.Fetch(src, id)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s: %w", id.StringFull(), err)
+	}
+	dir, err := install.TargetDir(id.Platform, serverInfo)
+	if err != nil {
+		return err
+	}
+	return tx.Add(types.Package{Id: id, Remote: &r}, dir)
+}
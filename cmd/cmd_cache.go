@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"lucy/cache"
+	"lucy/config"
+	"lucy/logger"
+	"lucy/tools"
+	"lucy/tui"
+
+	"github.com/urfave/cli/v3"
+)
+
+var subcmdCache = &cli.Command{
+	Name:  "cache",
+	Usage: "Inspect and manage the network cache and the package store",
+	Description: "Every subcommand applies to all caches, unless some are " +
+		"named as arguments (network, package).",
+	Commands: []*cli.Command{
+		{
+			Name:      "list",
+			Usage:     "List cache entries",
+			ArgsUsage: "[CACHE...]",
+			Flags:     []cli.Flag{flagJsonOutput, flagLongOutput},
+			Action:    tools.Decorate(actionCacheList, decoratorGlobalFlags),
+		},
+		{
+			Name:      "stats",
+			Usage:     "Show the size and usage of caches",
+			ArgsUsage: "[CACHE...]",
+			Flags:     []cli.Flag{flagJsonOutput},
+			Action:    tools.Decorate(actionCacheStats, decoratorGlobalFlags),
+		},
+		{
+			Name:      "clean",
+			Usage:     "Remove every cache entry",
+			ArgsUsage: "[CACHE...]",
+			Action:    tools.Decorate(actionCacheClean, decoratorGlobalFlags),
+		},
+		{
+			Name:      "prune",
+			Usage:     "Remove expired, old entries, or entries over a size",
+			ArgsUsage: "[CACHE...]",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "older-than",
+					Usage: "Remove entries older than `AGE`, e.g., 12h or 7d",
+				},
+				&cli.StringFlag{
+					Name:  "max-size",
+					Usage: "Remove the first entries to evict until each cache fits in `SIZE`",
+				},
+				flagJsonOutput,
+			},
+			Action: tools.Decorate(actionCachePrune, decoratorGlobalFlags),
+		},
+		{
+			Name:      "verify",
+			Usage:     "Re-hash cache entries and remove corrupted ones",
+			ArgsUsage: "[CACHE...]",
+			Flags:     []cli.Flag{flagJsonOutput},
+			Action:    tools.Decorate(actionCacheVerify, decoratorGlobalFlags),
+		},
+	},
+}
+
+var actionCacheList cli.ActionFunc = func(
+	ctx context.Context,
+	cmd *cli.Command,
+) error {
+	handlers, err := cacheHandlers(cmd)
+	if err != nil {
+		return err
+	}
+	var entries []cache.Entry
+	for _, h := range handlers {
+		entries = append(entries, h.Entries()...)
+	}
+
+	if cmd.Bool(flagJsonName) {
+		tools.PrintAsJson(tools.Ternary(entries == nil, []cache.Entry{}, entries))
+		return nil
+	}
+	if len(entries) == 0 {
+		logger.ShowInfo("cache is empty")
+		return nil
+	}
+
+	table := &tui.FieldTable{
+		Headers:      []string{"Cache", "Key", "Size", "Expiry"},
+		MaxCellWidth: tools.Ternary(cmd.Bool(flagLongName), 0, 60),
+	}
+	for _, e := range entries {
+		expiry := tools.Dim("-")
+		if e.Cache == cache.Package.Name() {
+			expiry = "used " + e.LastUsed.Format(time.DateTime)
+		} else if e.Expiration.Before(time.Now()) {
+			expiry = tools.Yellow("expired")
+		} else {
+			expiry = e.Expiration.Format(time.DateTime)
+		}
+		table.Rows = append(
+			table.Rows,
+			[]string{e.Cache, entryLabel(e), tools.HumanBytes(e.Size), expiry},
+		)
+	}
+	tui.Flush(&tui.Data{Fields: []tui.Field{table}})
+	return nil
+}
+
+var actionCacheStats cli.ActionFunc = func(
+	ctx context.Context,
+	cmd *cli.Command,
+) error {
+	handlers, err := cacheHandlers(cmd)
+	if err != nil {
+		return err
+	}
+	var stats []cache.Stats
+	for _, h := range handlers {
+		stats = append(stats, h.Stats())
+	}
+
+	if cmd.Bool(flagJsonName) {
+		tools.PrintAsJson(stats)
+		return nil
+	}
+
+	out := &tui.Data{}
+	for i, s := range stats {
+		if i != 0 {
+			out.Fields = append(out.Fields, &tui.FieldSeparator{Dim: true})
+		}
+		out.Fields = append(
+			out.Fields,
+			&tui.FieldAnnotatedShortText{
+				Title:      "Cache",
+				Text:       s.Cache,
+				Annotation: tools.Ternary(s.Enabled, "", "disabled"),
+			},
+			&tui.FieldShortText{Title: "Directory", Text: s.Dir},
+			&tui.FieldShortText{Title: "Entries", Text: strconv.Itoa(s.Entries)},
+			&tui.FieldAnnotatedShortText{
+				Title: "Size",
+				Text:  tools.HumanBytes(s.Size),
+				Annotation: fmt.Sprintf(
+					"of %s, %.0f%%",
+					tools.HumanBytes(s.MaxSize),
+					100*float64(s.Size)/float64(max(s.MaxSize, 1)),
+				),
+			},
+		)
+		if s.Cache != cache.Package.Name() {
+			out.Fields = append(
+				out.Fields,
+				&tui.FieldShortText{Title: "Expired", Text: strconv.Itoa(s.Expired)},
+			)
+		}
+	}
+	tui.Flush(out)
+	return nil
+}
+
+var actionCacheClean cli.ActionFunc = func(
+	ctx context.Context,
+	cmd *cli.Command,
+) error {
+	handlers, err := cacheHandlers(cmd)
+	if err != nil {
+		return err
+	}
+	for _, h := range handlers {
+		if err := h.ClearAll(); err != nil {
+			return err
+		}
+		logger.ShowInfo("cleared " + h.Name() + " cache")
+	}
+	return nil
+}
+
+var actionCachePrune cli.ActionFunc = func(
+	ctx context.Context,
+	cmd *cli.Command,
+) error {
+	handlers, err := cacheHandlers(cmd)
+	if err != nil {
+		return err
+	}
+	var age time.Duration
+	if s := cmd.String("older-than"); s != "" {
+		if age, err = parseAge(s); err != nil {
+			return err
+		}
+	}
+	var size int64
+	if s := cmd.String("max-size"); s != "" {
+		if size, err = config.ParseSize(s); err != nil {
+			return err
+		}
+	}
+
+	var dropped []cache.Entry
+	for _, h := range handlers {
+		dropped = append(dropped, h.Prune(age, size)...)
+	}
+	return reportDropped(cmd, "pruned", dropped, -1)
+}
+
+var actionCacheVerify cli.ActionFunc = func(
+	ctx context.Context,
+	cmd *cli.Command,
+) error {
+	handlers, err := cacheHandlers(cmd)
+	if err != nil {
+		return err
+	}
+	checked := 0
+	var dropped []cache.Entry
+	for _, h := range handlers {
+		n, d := h.Verify()
+		checked += n
+		dropped = append(dropped, d...)
+	}
+	return reportDropped(cmd, "corrupted", dropped, checked)
+}
+
+// reportDropped prints the entries removed by prune or verify. A negative
+// checked count is not shown.
+func reportDropped(
+	cmd *cli.Command,
+	reason string,
+	dropped []cache.Entry,
+	checked int,
+) error {
+	if cmd.Bool(flagJsonName) {
+		result := struct {
+			Checked *int          `json:"checked,omitempty"`
+			Dropped []cache.Entry `json:"dropped"`
+		}{
+			Checked: tools.Ternary(checked < 0, nil, &checked),
+			Dropped: tools.Ternary(dropped == nil, []cache.Entry{}, dropped),
+		}
+		tools.PrintAsJson(result)
+		return nil
+	}
+	var freed int64
+	for _, e := range dropped {
+		logger.ShowInfo(fmt.Sprintf("removed %s %s entry %s", reason, e.Cache, entryLabel(e)))
+		freed += e.Size
+	}
+	summary := fmt.Sprintf("%d entries removed, %s freed", len(dropped), tools.HumanBytes(freed))
+	if checked >= 0 {
+		summary = fmt.Sprintf("%d entries checked, ", checked) + summary
+	}
+	logger.ShowInfo(summary)
+	return nil
+}
+
+// entryLabel names the entry for users. Objects in the package store are keyed
+// by hashes, which are long and meaningless, so their ids are shown instead.
+func entryLabel(e cache.Entry) string {
+	if e.Cache != cache.Package.Name() {
+		return e.Key
+	}
+	return tools.Ternary(len(e.Ids) == 0, e.Filename, strings.Join(e.Ids, ", "))
+}
+
+// cacheHandlers returns the caches named in the arguments, or all of them.
+func cacheHandlers(cmd *cli.Command) ([]cache.Handler, error) {
+	if cmd.Args().Len() == 0 {
+		return cache.All, nil
+	}
+	var handlers []cache.Handler
+	for _, name := range cmd.Args().Slice() {
+		i := slices.IndexFunc(cache.All, func(h cache.Handler) bool { return h.Name() == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown cache %s, expected network or package", name)
+		}
+		handlers = append(handlers, cache.All[i])
+	}
+	return handlers, nil
+}
+
+// parseAge is time.ParseDuration with days, which ages are usually given in.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age: %s", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age: %s", s)
+	}
+	return d, nil
+}
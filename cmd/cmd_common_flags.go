@@ -1,9 +1,17 @@
 package cmd
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/urfave/cli/v3"
+	"lucy/config"
+	"lucy/syntax"
+	"lucy/tools"
 	"lucy/types"
 )
 
@@ -12,6 +20,7 @@ const (
 	flagLongName    = "long"
 	flagNoStyleName = "no-style"
 	flagSourceName  = "source"
+	flagFromName    = "from"
 )
 
 var flagJsonOutput = &cli.BoolFlag{
@@ -40,8 +49,91 @@ var flagSource = &cli.StringFlag{
 	},
 }
 
+// sourceFlag returns the --source flag, or the source.default key when the
+// flag is not given.
+func sourceFlag(cmd *cli.Command) string {
+	if cmd.IsSet(flagSourceName) {
+		return cmd.String(flagSourceName)
+	}
+	return config.String(config.KeySourceDefault)
+}
+
 var flagNoStyle = &cli.BoolFlag{
 	Name:  flagNoStyleName,
 	Usage: "Disable colored and styled output",
 	Value: false,
 }
+
+var flagFrom = &cli.StringFlag{
+	Name:  flagFromName,
+	Usage: "Also read packages from `FILE`, one per line, or from stdin if it is -",
+}
+
+// parseIds parses the package ids in args and in the --from file. An argument
+// of - reads ids from stdin as well. Blank lines and lines starting with # are
+// skipped. All invalid ids are reported at once. Stdin can only be read once,
+// so - must not be given twice, as an argument or to --from.
+//
+// TODO: remove and upgrade should take many ids through parseIds as well, as
+// add, info and prefetch do. Neither command exists yet.
+func parseIds(cmd *cli.Command, args []string) (ids []types.PackageId, err error) {
+	stdin := tools.Ternary(cmd.String(flagFromName) == "-", 1, 0)
+	for _, arg := range args {
+		if arg == "-" {
+			stdin++
+		}
+	}
+	if stdin > 1 {
+		return nil, errors.New("stdin can only be read once, give - either as an argument or to --from")
+	}
+	var errs []error
+	parse := func(where, s string) {
+		id, err := syntax.Parse(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s%w", where, err))
+			return
+		}
+		ids = append(ids, id)
+	}
+	readFrom := func(name string, r io.Reader) {
+		scanner := bufio.NewScanner(r)
+		for line := 1; scanner.Scan(); line++ {
+			s := strings.TrimSpace(scanner.Text())
+			if s == "" || strings.HasPrefix(s, "#") {
+				continue
+			}
+			parse(fmt.Sprintf("%s:%d: ", name, line), s)
+		}
+		if err := scanner.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot read %s: %w", name, err))
+		}
+	}
+
+	for _, arg := range args {
+		if arg == "-" {
+			readFrom("stdin", os.Stdin)
+			continue
+		}
+		parse("", arg)
+	}
+	switch from := cmd.String(flagFromName); from {
+	case "":
+	case "-":
+		readFrom("stdin", os.Stdin)
+	default:
+		file, err := os.Open(from)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		readFrom(from, file)
+	}
+
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("no package given")
+	}
+	return ids, nil
+}
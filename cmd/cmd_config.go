@@ -2,19 +2,191 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"lucy/config"
+	"lucy/remote"
+	"lucy/tools"
+	"lucy/tui"
 
 	"github.com/urfave/cli/v3"
 )
 
+var flagGlobalConfig = &cli.BoolFlag{
+	Name:    "global",
+	Aliases: []string{"g"},
+	Usage:   "Use the user config file rather than the one of the server",
+	Value:   false,
+}
+
 var subcmdConfig = &cli.Command{
-	Name:   "config",
-	Usage:  "Manage lucy's configurations",
-	Action: actionEmpty, // tools.Decorate(actionInit, decorator),
+	Name:  "config",
+	Usage: "Manage lucy's configurations",
+	Description: "Settings are resolved from, in increasing precedence, the defaults, " +
+		"the user config file, the .lucy/config.json of the server, LUCY_* " +
+		"environment variables, and flags.",
+	Commands: []*cli.Command{
+		{
+			Name:      "get",
+			Usage:     "Print the value of a key",
+			ArgsUsage: "KEY",
+			Action: tools.Decorate(
+				actionConfigGet,
+				decoratorGlobalFlags,
+				decoratorHelpAndExitOnNoArg,
+			),
+		},
+		{
+			Name:      "set",
+			Usage:     "Set a key in the config file",
+			ArgsUsage: "KEY VALUE",
+			Flags:     []cli.Flag{flagGlobalConfig},
+			Action: tools.Decorate(
+				actionConfigSet,
+				decoratorGlobalFlags,
+				decoratorHelpAndExitOnNoArg,
+			),
+		},
+		{
+			Name:      "unset",
+			Usage:     "Remove a key from the config file",
+			ArgsUsage: "KEY",
+			Flags:     []cli.Flag{flagGlobalConfig},
+			Action: tools.Decorate(
+				actionConfigUnset,
+				decoratorGlobalFlags,
+				decoratorHelpAndExitOnNoArg,
+			),
+		},
+		{
+			Name:  "list",
+			Usage: "List all keys with their values and origins",
+			Flags: []cli.Flag{flagJsonOutput},
+			Action: tools.Decorate(
+				actionConfigList,
+				decoratorGlobalFlags,
+			),
+		},
+	},
+}
+
+var errorConfigArgs = errors.New("wrong number of arguments")
+
+var actionConfigGet cli.ActionFunc = func(
+	ctx context.Context,
+	cmd *cli.Command,
+) error {
+	name := cmd.Args().First()
+	if _, ok := config.Lookup(name); !ok {
+		return fmt.Errorf("%w: %s", config.ErrorUnknownKey, name)
+	}
+	fmt.Println(config.String(name))
+	return nil
+}
+
+var actionConfigSet cli.ActionFunc = func(
+	ctx context.Context,
+	cmd *cli.Command,
+) error {
+	if cmd.Args().Len() != 2 {
+		return fmt.Errorf("%w, expected KEY VALUE", errorConfigArgs)
+	}
+	return config.Set(
+		configFile(cmd),
+		cmd.Args().Get(0),
+		cmd.Args().Get(1),
+	)
 }
 
-var actionConfig cli.ActionFunc = func(
+var actionConfigUnset cli.ActionFunc = func(
 	ctx context.Context,
 	cmd *cli.Command,
 ) error {
+	return config.Unset(configFile(cmd), cmd.Args().First())
+}
+
+var actionConfigList cli.ActionFunc = func(
+	ctx context.Context,
+	cmd *cli.Command,
+) error {
+	type entry struct {
+		Key    string `json:"key"`
+		Kind   string `json:"kind"`
+		Value  string `json:"value"`
+		Layer  string `json:"layer"`
+		Origin string `json:"origin,omitempty"`
+	}
+	var entries []entry
+	for _, v := range config.All() {
+		entries = append(
+			entries, entry{
+				Key:    v.Key.Name,
+				Kind:   v.Key.Kind.String(),
+				Value:  maskSecret(v),
+				Layer:  v.Layer.String(),
+				Origin: v.Origin,
+			},
+		)
+	}
+
+	if cmd.Bool(flagJsonName) {
+		tools.PrintAsJson(entries)
+		return nil
+	}
+
+	out := &tui.Data{}
+	for _, e := range entries {
+		out.Fields = append(
+			out.Fields, &tui.FieldAnnotatedShortText{
+				Title: e.Key,
+				Text:  tools.Ternary(e.Value == "", "-", e.Value),
+				Annotation: tools.Ternary(
+					e.Origin == "",
+					e.Layer,
+					e.Layer+", "+e.Origin,
+				),
+			},
+		)
+	}
+	tui.Flush(out)
 	return nil
 }
+
+func configFile(cmd *cli.Command) string {
+	if cmd.Bool(flagGlobalConfig.Name) {
+		return config.UserFile()
+	}
+	return config.ServerFile()
+}
+
+func maskSecret(v config.Value) string {
+	if !v.Key.Secret || v.Value == "" {
+		return v.Value
+	}
+	return strings.Repeat("*", 8)
+}
+
+// interactive tells whether prompts may be opened without being asked for.
+func interactive() bool {
+	return config.String(config.KeyInteractive) != config.InteractiveNever &&
+		tools.IsTerminal()
+}
+
+// prioritized orders the sources by the source.priority key. Sources not in the
+// key keep their order, after those that are.
+func prioritized(sources []remote.SourceHandler) []remote.SourceHandler {
+	priority := config.List(config.KeySourcePriority)
+	rank := func(s remote.SourceHandler) int {
+		i := slices.Index(priority, s.Name().String())
+		return tools.Ternary(i < 0, len(priority), i)
+	}
+	sorted := slices.Clone(sources)
+	slices.SortStableFunc(
+		sorted,
+		func(a, b remote.SourceHandler) int { return rank(a) - rank(b) },
+	)
+	return sorted
+}
@@ -2,8 +2,17 @@ package cmd
 
 import (
 	"context"
+	"errors"
 
+	"lucy/cache"
+	"lucy/config"
+	"lucy/dependency"
+	"lucy/github"
+	"lucy/remote/mcdr"
+	"lucy/remote/modrinth"
+	"lucy/remote/mojang"
 	"lucy/tools"
+	"lucy/util"
 
 	"lucy/logger"
 
@@ -40,6 +49,26 @@ func decoratorGlobalFlags(f cli.ActionFunc) cli.ActionFunc {
 		if cmd.Bool("no-style") {
 			tools.TurnOffStyles()
 		}
+		// Bad entries are skipped rather than fatal, so that they can still
+		// be fixed with `lucy config`. The same goes for applying them.
+		if err := config.Load(); err != nil {
+			for _, err := range unjoin(err) {
+				logger.ReportWarn(err)
+			}
+		}
+		if proxy := cmd.String("proxy"); proxy != "" {
+			if err := config.SetFlag(config.KeyHttpProxy, proxy, "proxy"); err != nil {
+				return err
+			}
+		}
+		if cmd.Bool("offline") {
+			if err := config.SetFlag(config.KeyOffline, "true", "offline"); err != nil {
+				return err
+			}
+		}
+		if err := applyConfig(); err != nil {
+			logger.ReportWarn(err)
+		}
 		return f(ctx, cmd)
 	}
 }
@@ -48,10 +77,11 @@ func decoratorGlobalFlags(f cli.ActionFunc) cli.ActionFunc {
 // returns a cli.ActionFunc that prints help and exit when there's no args specified.
 //
 // This function is not necessarily applicable to every action function, as some
-// sub-commands are expected to have no args, e.g., `lucy status`.
+// sub-commands are expected to have no args, e.g., `lucy status`. Packages read
+// with --from count as args.
 func decoratorHelpAndExitOnNoArg(f cli.ActionFunc) cli.ActionFunc {
 	return func(ctx context.Context, cmd *cli.Command) error {
-		if cmd.Args().Len() == 0 {
+		if cmd.Args().Len() == 0 && cmd.String(flagFromName) == "" {
 			cli.ShowSubcommandHelpAndExit(cmd, 0)
 		}
 		return f(ctx, cmd)
@@ -70,17 +100,34 @@ func decoratorHelpAndExitOnNoFlag(f cli.ActionFunc) cli.ActionFunc {
 	}
 }
 
+// decoratorLogAndExitOnError reports the error, and returns it marked as
+// reported, so that main only exits with an error code for scripts to tell.
 func decoratorLogAndExitOnError(f cli.ActionFunc) cli.ActionFunc {
 	return func(ctx context.Context, cmd *cli.Command) error {
 		err := f(ctx, cmd)
 		if err != nil {
 			logger.ReportError(err)
-			return err
+			return reportedError{err}
 		}
 		return nil
 	}
 }
 
+// reportedError is an error already reported to the user.
+type reportedError struct {
+	error
+}
+
+func (e reportedError) Unwrap() error {
+	return e.error
+}
+
+// Reported tells whether the error returned by Cli was already reported to
+// the user.
+func Reported(err error) bool {
+	return errors.As(err, &reportedError{})
+}
+
 // decoratorHelpAndExitOnError exits with an error code and prints the help
 //
 // This means, with this decorator, you MUST NOT throw unexpected errors
@@ -100,3 +147,44 @@ func decoratorHelpAndExitOnError(f cli.ActionFunc) cli.ActionFunc {
 		return err
 	}
 }
+
+// applyConfig hands the resolved config to the packages that use it.
+func applyConfig() error {
+	for host, base := range config.Map(config.KeyMirrors) {
+		if err := util.SetMirror(host, base); err != nil {
+			return err
+		}
+	}
+	cache.Network.SetMaxSize(config.Size(config.KeyCacheNetworkSize))
+	cache.Package.SetMaxSize(config.Size(config.KeyCachePackageSize))
+	github.SetToken(config.String(config.KeyGithubToken))
+	modrinth.SetToken(config.String(config.KeyModrinthToken))
+	util.SetOffline(config.Bool(config.KeyOffline))
+	client, err := util.ConfigureHttp(
+		util.HttpOptions{
+			ConnectTimeout:     config.Duration(config.KeyHttpConnectTimeout),
+			ReadTimeout:        config.Duration(config.KeyHttpReadTimeout),
+			Proxy:              config.String(config.KeyHttpProxy),
+			CaFile:             config.String(config.KeyHttpCaFile),
+			InsecureSkipVerify: config.Bool(config.KeyHttpInsecure),
+		},
+	)
+	if err != nil {
+		// Other values are validated on loading, only the ca file can fail
+		v := config.Get(config.KeyHttpCaFile)
+		return &config.Error{Origin: v.Origin, Key: v.Key.Name, Err: err}
+	}
+	requester := util.NewRequester(client)
+	modrinth.SetRequester(requester)
+	mcdr.SetRequester(requester)
+	dependency.SetReleaseOrder(mojang.ReleaseOrder)
+	return nil
+}
+
+// unjoin splits an error made by errors.Join.
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
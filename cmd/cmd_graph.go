@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"lucy/dependency"
+	"lucy/tools"
+	"lucy/types"
+
+	"github.com/urfave/cli/v3"
+)
+
+// Graph formats shared by `lucy tree` and `lucy why`. DOT and Mermaid are
+// meant to be pasted into documentation, so they are never styled.
+const (
+	graphFormatText    = "text"
+	graphFormatDot     = "dot"
+	graphFormatMermaid = "mermaid"
+)
+
+var flagGraphFormat = &cli.StringFlag{
+	Name:  "format",
+	Usage: "Print the graph as `FORMAT`, one of text, dot, mermaid",
+	Value: graphFormatText,
+	Validator: func(s string) error {
+		switch s {
+		case graphFormatText, graphFormatDot, graphFormatMermaid:
+			return nil
+		}
+		return errors.New("must be one of \"text\", \"dot\", \"mermaid\"")
+	},
+}
+
+// resolveNode returns the key of the node for the package given on the command
+// line. An installed package is preferred, so that a plugin is found without
+// its platform.
+func resolveNode(g *dependency.Graph, id types.PackageId, serverInfo types.ServerInfo) (string, error) {
+	key := dependency.Key(id)
+	if p, ok := findInstalled(id, serverInfo); ok {
+		key = dependency.Key(p.Id)
+	}
+	if _, ok := g.Nodes[key]; !ok {
+		return "", fmt.Errorf("%s is neither installed nor required by an installed package", id.StringFull())
+	}
+	return key, nil
+}
+
+// nodeLabel is the name of the node, with the version if it is installed.
+func nodeLabel(g *dependency.Graph, key string) string {
+	n := g.Nodes[key]
+	if !n.Installed {
+		return key
+	}
+	return key + " " + n.Id.Version.String()
+}
+
+// edgeLabel is the constraint of the edge, left empty if any version would do.
+func edgeLabel(e dependency.Edge) string {
+	if len(e.Constraint) == 0 {
+		return ""
+	}
+	return e.Constraint.String()
+}
+
+func sortedKeys(g *dependency.Graph) []string {
+	keys := make([]string, 0, len(g.Nodes))
+	for key := range g.Nodes {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// graphDot renders the graph in Graphviz DOT. Optional dependencies are dashed
+// and unsatisfied packages are red.
+func graphDot(g *dependency.Graph) string {
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, key := range sortedKeys(g) {
+		attrs := "label=" + quote(nodeLabel(g, key))
+		if g.Unsatisfied(key) {
+			attrs += ", color=red, fontcolor=red"
+		} else if !g.Nodes[key].Installed {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", quote(key), attrs)
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		if label := edgeLabel(e); label != "" {
+			attrs = append(attrs, "label="+quote(label))
+		}
+		if !e.Mandatory {
+			attrs = append(attrs, "style=dashed")
+		}
+		if e.Unsatisfied() {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(&b, "  %s -> %s", quote(e.From), quote(e.To))
+		if len(attrs) != 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// graphMermaid renders the graph as a Mermaid flowchart. Node ids are numbered,
+// as package names may contain characters Mermaid does not allow in them.
+// Optional dependencies are dotted and unsatisfied packages are red.
+func graphMermaid(g *dependency.Graph) string {
+	escape := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace
+	keys := sortedKeys(g)
+	ids := make(map[string]string, len(keys))
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	var unsatisfied []string
+	for i, key := range keys {
+		ids[key] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[key], escape(nodeLabel(g, key)))
+		if g.Unsatisfied(key) {
+			unsatisfied = append(unsatisfied, ids[key])
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := tools.Ternary(e.Mandatory, "-->", "-.->")
+		if label := edgeLabel(e); label != "" {
+			arrow += "|\"" + escape(label) + "\"|"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+	}
+	if len(unsatisfied) != 0 {
+		b.WriteString("  classDef unsatisfied stroke:#d00,color:#d00\n")
+		fmt.Fprintf(&b, "  class %s unsatisfied\n", strings.Join(unsatisfied, ","))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -2,14 +2,15 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 
 	"lucy/remote/source"
 
 	"lucy/logger"
+	"lucy/probe"
 	"lucy/remote"
-	"lucy/syntax"
 	"lucy/tools"
 	"lucy/tui"
 	"lucy/types"
@@ -18,10 +19,16 @@ import (
 )
 
 var subcmdInfo = &cli.Command{
-	Name:  "info",
-	Usage: "Display information of a mod or plugin",
+	Name:      "info",
+	Usage:     "Display information of mods or plugins",
+	ArgsUsage: "PACKAGE...",
 	Flags: []cli.Flag{
 		flagSource,
+		flagFrom,
+		&cli.BoolFlag{
+			Name:  "local",
+			Usage: "Show the installed package instead of the remote one",
+		},
 		flagJsonOutput,
 		flagLongOutput,
 		flagNoStyle,
@@ -38,14 +45,57 @@ var actionInfo cli.ActionFunc = func(
 	ctx context.Context,
 	cmd *cli.Command,
 ) error {
-	id := syntax.Parse(cmd.Args().First())
+	ids, err := parseIds(cmd, cmd.Args().Slice())
+	if err != nil {
+		return err
+	}
+	var errs []error
+	var results jsonResults
+	for i, id := range ids {
+		if i != 0 && !cmd.Bool(flagJsonOutput.Name) {
+			fmt.Println()
+		}
+		errs = append(errs, showInfo(cmd, id, &results))
+	}
+	if cmd.Bool(flagJsonOutput.Name) {
+		results.print(len(ids) > 1)
+	}
+	return errors.Join(errs...)
+}
+
+// jsonResults collects the JSON output of each package, so that several
+// packages are printed as one array.
+type jsonResults []any
+
+// print prints the results, as an array if asked for by several packages.
+func (r jsonResults) print(several bool) {
+	switch {
+	case several:
+		tools.PrintAsJson(tools.Ternary(r == nil, jsonResults{}, r))
+	case len(r) == 1:
+		tools.PrintAsJson(r[0])
+	}
+}
+
+// showInfo prints the information of a single package. With --json, the
+// output is added to results instead.
+func showInfo(cmd *cli.Command, id types.PackageId, results *jsonResults) error {
 	p := id.NewPackage()
+	if cmd.Bool("local") {
+		installed, ok := findInstalled(id, probe.ServerInfo())
+		if !ok {
+			err := fmt.Errorf("%w: %s is not installed", remote.ErrorNoPackage, id.StringFull())
+			logger.ReportError(err)
+			return err
+		}
+		return printLocalInfo(cmd, installed, results)
+	}
 
 	var out *tui.Data
 	var err error
 
 	if id.Platform == types.AnyPlatform {
-		for _, source := range source.All {
+		for _, source := range prioritized(source.All) {
 			info, err := remote.Information(source, id.Name)
 			if err != nil {
 				continue
@@ -63,28 +113,24 @@ var actionInfo cli.ActionFunc = func(
 	} else if id.Platform.IsModding() {
 		info, err := remote.Information(source.Modrinth, id.Name)
 		if err != nil {
-			logger.ReportError(err)
-		}
-		p.Information = &info
-
-		remote, err := remote.Fetch(source.Modrinth, id)
-		p.Remote = &remote
-		if err != nil {
-			logger.ReportError(err)
-			return err
+			logger.Info(err)
+		} else if remote, err := remote.Fetch(source.Modrinth, id); err != nil {
+			logger.Info(err)
+		} else {
+			p.Information, p.Remote = &info, &remote
+			out = infoOutput(p, cmd.Bool(flagLongOutput.Name))
 		}
-		out = infoOutput(p, cmd.Bool(flagLongOutput.Name))
 	} else if id.Platform == types.Mcdr {
 		info, err := remote.Information(
 			source.Mcdr,
 			id.Name,
 		)
 		if err != nil {
-			logger.ReportWarn(err)
+			logger.Info(err)
 		} else {
 			remote, err := remote.Fetch(source.Mcdr, id)
 			if err != nil {
-				logger.ReportWarn(err)
+				logger.Info(err)
 			} else {
 				p.Information, p.Remote = &info, &remote
 				out = infoOutput(p, cmd.Bool(flagLongOutput.Name))
@@ -97,12 +143,17 @@ var actionInfo cli.ActionFunc = func(
 		return err
 	}
 	if out == nil {
+		// A package of its own, or one taken down, is still worth showing
+		if installed, ok := findInstalled(id, probe.ServerInfo()); ok {
+			logger.ShowInfo(id.StringFull() + " is not found remotely, showing the installed package")
+			return printLocalInfo(cmd, installed, results)
+		}
 		err = fmt.Errorf("%w: %s", remote.ErrorNoPackage, id.StringFull())
 		logger.ReportError(err)
 		return err
 	}
 	if cmd.Bool(flagJsonOutput.Name) {
-		tools.PrintAsJson(p)
+		*results = append(*results, p)
 		return nil
 	}
 	tui.Flush(out)
@@ -120,11 +171,13 @@ func infoOutput(p *types.Package, longOutput bool) *tui.Data {
 		tools.TermHeight()*3/2,
 	)
 	useAlternate := !longOutput
+	annotation := "(installed)"
+	if p.Remote != nil {
+		annotation = "(from " + p.Remote.Source.Title() + ")"
+	}
 	o := &tui.Data{
 		Fields: []tui.Field{
-			&tui.FieldAnnotation{
-				Annotation: "(from " + p.Remote.Source.Title() + ")",
-			},
+			&tui.FieldAnnotation{Annotation: annotation},
 			&tui.FieldShortText{
 				Title: "Name",
 				Text:  p.Information.Title,
@@ -196,13 +249,18 @@ func infoOutput(p *types.Package, longOutput bool) *tui.Data {
 		)
 	}
 
-	o.Fields = append(
-		o.Fields, &tui.FieldAnnotatedShortText{
-			Title:      "Download",
-			Text:       tools.Underline(p.Remote.FileUrl),
-			Annotation: p.Remote.Filename,
-		},
-	)
+	if p.Remote != nil {
+		o.Fields = append(
+			o.Fields, &tui.FieldAnnotatedShortText{
+				Title:      "Download",
+				Text:       tools.Underline(p.Remote.FileUrl),
+				Annotation: p.Remote.Filename,
+			},
+		)
+	}
+	if p.Local != nil {
+		o.Fields = append(o.Fields, &tui.FieldShortText{Title: "File", Text: p.Local.Path})
+	}
 
 	// TODO: Put current server version on the top
 	// TODO: Hide snapshot versions, except if the current server is using it
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"slices"
+
+	"lucy/dependency"
+	"lucy/install"
+	"lucy/logger"
+	"lucy/probe"
+	"lucy/remote"
+	"lucy/remote/source"
+	"lucy/tools"
+	"lucy/tui"
+	"lucy/types"
+
+	"github.com/urfave/cli/v3"
+)
+
+// localDependency is a declared dependency of an installed package, checked
+// against the server.
+type localDependency struct {
+	Id         string `json:"id"`
+	Constraint string `json:"constraint"`
+	Mandatory  bool   `json:"mandatory"`
+	Inverse    bool   `json:"inverse,omitempty"` // an incompatibility
+	Status     string `json:"status"`
+	Installed  string `json:"installed,omitempty"`
+
+	status dependency.Status
+}
+
+// findInstalled looks the package up among those installed in the server. The
+// version of the id is ignored, as only one version can be installed.
+func findInstalled(id types.PackageId, serverInfo types.ServerInfo) (types.Package, bool) {
+	for _, p := range serverInfo.Packages {
+		if p.Id.Name != id.Name {
+			continue
+		}
+		if id.Platform == types.AnyPlatform || id.Platform == p.Id.Platform {
+			return p, true
+		}
+	}
+	return types.Package{}, false
+}
+
+// printLocalInfo shows the metadata read from the file of an installed
+// package, its dependencies, and the latest version of the remote project it
+// is linked to. With --json, the output is added to results instead.
+func printLocalInfo(cmd *cli.Command, p types.Package, results *jsonResults) error {
+	serverInfo := probe.ServerInfo()
+	var deps []localDependency
+	if p.Dependencies != nil {
+		for _, d := range p.Dependencies.Value {
+			status, installed := dependency.Check(d, serverInfo)
+			dep := localDependency{
+				Id:         d.Id.Name.String(),
+				Constraint: d.Constraint.String(),
+				Mandatory:  d.Mandatory,
+				Inverse:    d.Inverse,
+				Status:     status.String(),
+				status:     status,
+			}
+			if installed != types.NoVersion && installed != types.UnknownVersion {
+				dep.Installed = installed.String()
+			}
+			deps = append(deps, dep)
+		}
+	}
+	origin, untracked := originOf(p)
+	var src types.Source
+	var latest types.ProjectVersion
+	var linked bool
+	if untracked {
+		logger.Info(p.Id.StringFull() + " was installed from " + origin.Location + ", not tracked by any source")
+	} else {
+		src, latest, linked = latestLinked(p)
+	}
+
+	if cmd.Bool(flagJsonOutput.Name) {
+		out := struct {
+			Package      types.Package         `json:"package"`
+			Dependencies []localDependency     `json:"dependencies"`
+			Source       string                `json:"source,omitempty"`
+			Latest       *types.ProjectVersion `json:"latest,omitempty"`
+			Origin       *install.Origin       `json:"origin,omitempty"`
+		}{
+			Package:      p,
+			Dependencies: tools.Ternary(deps == nil, []localDependency{}, deps),
+		}
+		if linked {
+			out.Source, out.Latest = src.String(), &latest
+		}
+		if untracked {
+			out.Origin = &origin
+		}
+		*results = append(*results, out)
+		return nil
+	}
+
+	out := infoOutput(&p, cmd.Bool(flagLongOutput.Name))
+	version := &tui.FieldAnnotatedShortText{Title: "Version", Text: p.Id.Version.String()}
+	if linked {
+		version.Annotation = tools.Ternary(
+			latest.Version == p.Id.Version,
+			"latest on "+src.Title(),
+			"latest on "+src.Title()+" is "+latest.Version.String(),
+		)
+	}
+	if untracked {
+		version.Annotation = "not tracked by any source"
+	}
+	out.Fields = append(out.Fields, version)
+	if untracked {
+		out.Fields = append(out.Fields, &tui.FieldShortText{Title: "Origin", Text: origin.Location})
+	}
+
+	if len(deps) != 0 {
+		f := &tui.FieldMultiAnnotatedShortText{Title: "Dependencies", ShowTotal: true}
+		for _, d := range deps {
+			f.Texts = append(f.Texts, d.Id+" "+d.Constraint)
+			f.Annotations = append(f.Annotations, dependencyAnnotation(d))
+		}
+		out.Fields = append(out.Fields, f)
+	}
+	tui.Flush(out)
+	return nil
+}
+
+func dependencyAnnotation(d localDependency) string {
+	s := d.Status
+	if d.Installed != "" && d.status != dependency.StatusSatisfied {
+		s += ", " + d.Installed + " installed"
+	}
+	switch {
+	case d.Inverse:
+		s += tools.Ternary(d.Mandatory, ", incompatible", ", conflicting")
+	case !d.Mandatory:
+		s += ", optional"
+	}
+	switch d.status {
+	case dependency.StatusSatisfied:
+		return tools.Green(s)
+	case dependency.StatusMissing, dependency.StatusMismatch:
+		return tools.Red(s)
+	case dependency.StatusConflict:
+		return tools.Ternary(d.Mandatory, tools.Red(s), tools.Yellow(s))
+	case dependency.StatusAbsent:
+		return tools.Dim(s)
+	default:
+		return tools.Yellow(s)
+	}
+}
+
+// originOf returns where the installed package came from, if it was added
+// from a local file or a direct URL rather than a source.
+func originOf(p types.Package) (install.Origin, bool) {
+	if p.Local == nil {
+		return install.Origin{}, false
+	}
+	return install.OriginOf(p.Local.Path)
+}
+
+// latestLinked returns the latest release of the remote project the installed
+// package is linked to. A project of the same name is only taken as linked if
+// it lists the installed version, so that a namesake is never compared with.
+func latestLinked(p types.Package) (src types.Source, latest types.ProjectVersion, ok bool) {
+	handler := tools.Ternary[remote.SourceHandler](
+		p.Id.Platform == types.Mcdr,
+		source.Mcdr,
+		source.Modrinth,
+	)
+	versions, err := remote.Versions(handler, p.Id.Name)
+	if err != nil {
+		logger.Info(err)
+		return types.UnknownSource, types.ProjectVersion{}, false
+	}
+	supports := func(v types.ProjectVersion) bool {
+		return slices.ContainsFunc(
+			v.Platforms,
+			func(pl types.Platform) bool { return pl.Satisfy(p.Id.Platform) },
+		)
+	}
+	if !slices.ContainsFunc(
+		versions,
+		func(v types.ProjectVersion) bool { return v.Version == p.Id.Version },
+	) {
+		logger.Info("no remote project is linked to " + p.Id.StringFull())
+		return types.UnknownSource, types.ProjectVersion{}, false
+	}
+	// Versions are sorted newest first
+	for _, v := range versions {
+		if !v.Prerelease && supports(v) {
+			return handler.Name(), v, true
+		}
+	}
+	return types.UnknownSource, types.ProjectVersion{}, false
+}
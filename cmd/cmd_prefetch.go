@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"lucy/install"
+	"lucy/logger"
+	"lucy/probe"
+	"lucy/remote"
+	"lucy/tools"
+	"lucy/types"
+	"lucy/util"
+
+	"github.com/urfave/cli/v3"
+)
+
+var subcmdPrefetch = &cli.Command{
+	Name:      "prefetch",
+	Usage:     "Fetch packages and their dependencies for offline use",
+	ArgsUsage: "PACKAGE...",
+	Description: "Metadata is kept in the network cache and files in the package " +
+		"store, so that `lucy --offline add` can install the packages later. " +
+		"Run it in the server directory, so that the same versions as " +
+		"`lucy add` are picked.",
+	Flags: []cli.Flag{
+		flagSource,
+		flagFrom,
+		&cli.BoolFlag{
+			Name:  "optional",
+			Usage: "Also fetch optional dependencies",
+		},
+	},
+	Action: tools.Decorate(
+		actionPrefetch,
+		decoratorGlobalFlags,
+		decoratorHelpAndExitOnNoArg,
+	),
+}
+
+var actionPrefetch cli.ActionFunc = func(
+	ctx context.Context,
+	cmd *cli.Command,
+) error {
+	if util.Offline() {
+		return fmt.Errorf("%w: prefetch needs the network", util.ErrorOffline)
+	}
+	serverInfo := probe.ServerInfo()
+
+	ids, err := parseIds(cmd, cmd.Args().Slice())
+	if err != nil {
+		return err
+	}
+	var queue []packageHit
+	for _, id := range ids {
+		candidates, err := sourceCandidates(sourceFlag(cmd), id)
+		if err != nil {
+			return err
+		}
+		hits := findPackage(id, candidates, serverInfo)
+		if len(hits) == 0 {
+			return fmt.Errorf("%s not found in any source", id.StringFull())
+		}
+		queue = append(queue, hits[0])
+	}
+
+	// Dependencies are walked breadth first, each is looked up in the source
+	// of its dependent. Version constraints are not evaluated yet, so the
+	// version `lucy add` would pick for the dependency is fetched.
+	var pkgs []types.Package
+	var errs []error
+	seen := make(map[string]bool)
+	for len(queue) != 0 {
+		hit := queue[0]
+		queue = queue[1:]
+		key := hit.src.Name().String() + ":" + hit.id.Name.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		pkgs = append(pkgs, types.Package{Id: hit.id, Remote: &hit.remote})
+
+		// Warm the metadata `lucy info` shows as well
+		if _, err := remote.Information(hit.src, hit.id.Name); err != nil {
+			logger.Info(fmt.Errorf("cannot fetch information of %s: %w", hit.id.StringFull(), err))
+		}
+
+		deps, err := remote.Dependencies(hit.src, hit.id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot fetch dependencies of %s: %w", hit.id.StringFull(), err))
+			continue
+		}
+		for _, dep := range deps.Value {
+			// Incompatibilities are packages to keep away from, not to fetch
+			if dep.Inverse || (!dep.Mandatory && !cmd.Bool("optional")) {
+				continue
+			}
+			id := dep.Id
+			id.Version = types.AllVersion
+			r, err := remote.Fetch(hit.src, id)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("cannot fetch %s, needed by %s: %w", id.StringFull(), hit.id.StringFull(), err))
+				continue
+			}
+			queue = append(queue, packageHit{src: hit.src, id: id, remote: r})
+		}
+	}
+
+	fetched, err := install.Prefetch(pkgs)
+	errs = append(errs, err)
+	for _, p := range pkgs {
+		logger.ShowInfo("resolved " + p.Id.StringFull() + " from " + p.Remote.Source.Title())
+	}
+	logger.ShowInfo(
+		fmt.Sprintf(
+			"%d packages resolved, %d files downloaded",
+			len(pkgs), fetched,
+		),
+	)
+	return errors.Join(errs...)
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"lucy/remote"
 	"lucy/remote/source"
@@ -23,10 +25,9 @@ var subcmdSearch = &cli.Command{
 	Usage: "Search for mods and plugins",
 	Flags: []cli.Flag{
 		&cli.StringFlag{
-			Name:    "index",
-			Aliases: []string{"i"},
-			Usage:   "Index search results by `INDEX`",
-			Value:   "relevance",
+			Name:  "index",
+			Usage: "Index search results by `INDEX`",
+			Value: "relevance",
 			Validator: func(s string) error {
 				if types.SearchIndex(s).Valid() {
 					return nil
@@ -40,6 +41,12 @@ var subcmdSearch = &cli.Command{
 			Usage:   "Also show client-only mods in results",
 			Value:   false,
 		},
+		&cli.BoolFlag{
+			Name:    "interactive",
+			Aliases: []string{"i"},
+			Usage:   "Browse results interactively and pick packages to install",
+			Value:   false,
+		},
 		flagJsonOutput,
 		flagLongOutput,
 		flagNoStyle,
@@ -60,146 +67,204 @@ var (
 	errorInvalidPlatform   = errors.New("invalid platform")
 )
 
+// searchTimeout is shared by all sources queried in a single search.
+const searchTimeout = 15 * time.Second
+
 var actionSearch cli.ActionFunc = func(
-	_ context.Context,
+	ctx context.Context,
 	cmd *cli.Command,
 ) error {
-	p := syntax.Parse(cmd.Args().First())
+	p, err := syntax.Parse(cmd.Args().First())
+	if err != nil {
+		return err
+	}
 
 	showClientPackage := cmd.Bool("client")
 	indexBy := types.SearchIndex(cmd.String("index"))
 	options := types.SearchOptions{
 		ShowClientPackage: showClientPackage,
 		IndexBy:           indexBy,
+		Platform:          p.Platform,
 	}
-	sourceStr := cmd.String("source")
-	src := types.StringToSource(sourceStr)
+	sourceStr := sourceFlag(cmd)
 
-	out := &tui.Data{}
-	res := types.SearchResults{}
-	var err error
-
-	if src == types.AutoSource {
-		if p.Platform == types.AnyPlatform {
-			for _, sourceHandler := range source.All {
-				res, err = remote.Search(sourceHandler, p.Name, options)
-				if err != nil {
-					logger.ReportWarn(
-						fmt.Errorf(
-							"search on %s failed: %w",
-							sourceHandler.Name().Title(),
-							err,
-						),
-					)
-					continue
-				}
-				appendToSearchOutput(out, cmd.Bool("long"), res)
-			}
-		} else if p.Platform.IsModding() {
-			res, err = remote.Search(source.Modrinth, p.Name, options)
-			if err != nil && !errors.Is(err, remote.ErrorNoResults) {
-				logger.Fatal(err)
-			}
-			appendToSearchOutput(out, cmd.Bool("long"), res)
-		} else if p.Platform == types.Mcdr {
-			res, err = remote.Search(source.Mcdr, p.Name, options)
-			if err != nil && !errors.Is(err, remote.ErrorNoResults) {
-				logger.Fatal(err)
-			}
-			appendToSearchOutput(out, cmd.Bool("long"), res)
-		} else if !p.Platform.Valid() {
-			logger.Fatal(
-				fmt.Errorf(
-					"%w: %s",
-					errorInvalidPlatform,
-					p.Platform,
-				),
-			)
-		}
-	} else {
-		if src == types.UnknownSource {
-			logger.Fatal(
-				fmt.Errorf(
-					"%w: %s",
-					errorUnknownSource,
-					sourceStr,
-				),
-			)
-		}
-		sourceHandler, ok := source.Map[src]
-		if !ok {
-			logger.Fatal(
-				fmt.Errorf(
-					"%w: %s",
-					errorUnsupportedSource,
-					src.Title(),
-				),
-			)
-		}
-		res, err = remote.Search(
-			sourceHandler,
-			p.Name,
-			options,
-		)
-		if err != nil && !errors.Is(err, remote.ErrorNoResults) {
-			logger.Fatal(err)
-		}
+	sources, err := searchSources(p, sourceStr)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Bool("interactive") {
+		return browse(ctx, p.Name.String(), sources, options)
 	}
 
+	res, warnings, err := remote.SearchMultiple(
+		ctx,
+		sources,
+		p.Name,
+		options,
+		searchTimeout,
+	)
+	for _, warning := range warnings {
+		logger.ReportWarn(warning)
+	}
 	if errors.Is(err, remote.ErrorNoResults) {
 		logger.ShowInfo("no results found")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if cmd.Bool(flagJsonName) {
+		tools.PrintAsJson(res)
+		return nil
 	}
 
+	out := &tui.Data{}
+	appendToSearchOutput(out, cmd.Bool(flagLongName), res)
 	tui.Flush(out)
 	return nil
 }
 
+// searchSources determines which sources are applicable to the search. An
+// explicitly specified source always takes precedence over the platform.
+func searchSources(
+	p types.PackageId,
+	sourceStr string,
+) (sources []remote.SourceHandler, err error) {
+	src := types.StringToSource(sourceStr)
+	switch src {
+	case types.AutoSource:
+	case types.UnknownSource:
+		return nil, fmt.Errorf("%w: %s", errorUnknownSource, sourceStr)
+	default:
+		sourceHandler, ok := source.Map[src]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errorUnsupportedSource, src.Title())
+		}
+		return []remote.SourceHandler{sourceHandler}, nil
+	}
+
+	switch {
+	case p.Platform == types.AnyPlatform:
+		return prioritized(source.All), nil
+	case p.Platform.IsModding():
+		return []remote.SourceHandler{source.Modrinth}, nil
+	case p.Platform == types.Mcdr:
+		return []remote.SourceHandler{source.Mcdr}, nil
+	case !p.Platform.Valid():
+		return nil, fmt.Errorf("%w: %s", errorInvalidPlatform, p.Platform)
+	default:
+		return nil, fmt.Errorf("%w: %s", errorUnsupportedSource, p.Platform.Title())
+	}
+}
+
 func appendToSearchOutput(
 	out *tui.Data,
 	showAll bool,
 	res types.SearchResults,
 ) {
-	var results []string
+	// Count per source, in the order they first appear
+	var sources []types.Source
+	count := make(map[types.Source]int)
 	for _, r := range res.Results {
-		results = append(results, r.String())
-	}
-
-	if len(out.Fields) != 0 {
-		out.Fields = append(
-			out.Fields, &tui.FieldSeparator{
-				Length:       0,
-				Proportional: false,
-				Dim:          true,
-			},
-		)
+		if count[r.Source] == 0 {
+			sources = append(sources, r.Source)
+		}
+		count[r.Source]++
 	}
+	multiSource := len(sources) > 1
 
-	if res.Source == types.Modrinth && len(res.Results) == 100 {
+	if count[types.Modrinth] == 100 {
 		out.Fields = append(
 			out.Fields,
 			&tui.FieldAnnotation{
-				Annotation: "* only showing the top 100",
+				Annotation: "* only showing the top 100 from Modrinth",
 			},
 		)
 	}
 
+	var summary []string
+	for _, s := range sources {
+		summary = append(summary, s.Title()+tools.Dim(" "+strconv.Itoa(count[s])+" results"))
+	}
+
+	table := &tui.FieldTable{
+		Headers: []string{"Name", "Title", "Downloads", "Game Versions", "Side"},
+		MaxLines: tools.Ternary(
+			showAll,
+			0,
+			tools.TermHeight()-6,
+		),
+		MaxCellWidth: 32,
+	}
+	if multiSource {
+		table.Headers = tools.Insert(table.Headers, 1, "Source")
+	}
+	for _, r := range res.Results {
+		row := []string{
+			r.Name.String(),
+			r.Title,
+			tools.Ternary(r.Downloads == 0, tools.Dim("-"), humanCount(r.Downloads)),
+			versionSpan(r.GameVersions),
+			sideLabel(r.ClientSide, r.ServerSide),
+		}
+		if multiSource {
+			row = tools.Insert(row, 1, r.Source.String())
+		}
+		table.Rows = append(table.Rows, row)
+		if showAll {
+			table.SubRows = append(table.SubRows, r.Brief)
+		}
+	}
+
 	out.Fields = append(
 		out.Fields,
 		&tui.FieldShortText{
 			Title: ">>>",
-			Text:  res.Source.Title() + tools.Dim(" "+strconv.Itoa(len(res.Results))+" results"),
-		},
-		&tui.FieldDynamicColumnLabels{
-			Title:  "",
-			Labels: results,
-			MaxLines: tools.Ternary(
-				showAll,
-				0,
-				tools.TermHeight()-6,
-			),
-			MaxColumns: 5,
-			ShowTotal:  false,
-			NoTitle:    true,
+			Text:  strings.Join(summary, ", "),
 		},
+		table,
 	)
 }
+
+// humanCount abbreviates large numbers, e.g., 1234567 to 1.2M.
+func humanCount(n int) string {
+	switch {
+	case n >= 1_000_000_000:
+		return strconv.FormatFloat(float64(n)/1e9, 'f', 1, 64) + "B"
+	case n >= 1_000_000:
+		return strconv.FormatFloat(float64(n)/1e6, 'f', 1, 64) + "M"
+	case n >= 1_000:
+		return strconv.FormatFloat(float64(n)/1e3, 'f', 1, 64) + "k"
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// versionSpan shows the first and the last game versions. Sources list game
+// versions in ascending order.
+func versionSpan(versions []types.RawVersion) string {
+	switch len(versions) {
+	case 0:
+		return tools.Dim("-")
+	case 1:
+		return versions[0].String()
+	default:
+		return versions[0].String() + " – " + versions[len(versions)-1].String()
+	}
+}
+
+func sideLabel(client, server types.SideSupport) string {
+	switch {
+	case client == types.SideUnknown && server == types.SideUnknown:
+		return tools.Dim("-")
+	case server == types.SideUnknown:
+		return "unknown"
+	case !server.Supported():
+		return "client"
+	case client == types.SideRequired:
+		return "client & server"
+	default:
+		return "server"
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"lucy/dependency"
+	"lucy/probe"
+	"lucy/syntax"
+	"lucy/tools"
+
+	"github.com/urfave/cli/v3"
+)
+
+var subcmdTree = &cli.Command{
+	Name:      "tree",
+	Usage:     "Display the dependency tree of installed packages",
+	ArgsUsage: "[PACKAGE]",
+	Description: "Without a package, the tree of every package no other " +
+		"package depends on is shown. Dependencies come from the metadata of " +
+		"the installed files.",
+	Flags: []cli.Flag{
+		flagGraphFormat,
+		flagJsonOutput,
+		flagNoStyle,
+	},
+	Action: tools.Decorate(
+		actionTree,
+		decoratorGlobalFlags,
+		decoratorLogAndExitOnError,
+	),
+}
+
+// treeNode is a package in the JSON output of `lucy tree`. The edge it is
+// reached through is flattened into it.
+type treeNode struct {
+	Id           string     `json:"id"`
+	Version      string     `json:"version,omitempty"`
+	Constraint   string     `json:"constraint,omitempty"`
+	Optional     bool       `json:"optional,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	Cycle        bool       `json:"cycle,omitempty"`
+	Dependencies []treeNode `json:"dependencies,omitempty"`
+}
+
+var actionTree cli.ActionFunc = func(
+	_ context.Context,
+	cmd *cli.Command,
+) error {
+	serverInfo := probe.ServerInfo()
+	g := dependency.NewGraph(serverInfo)
+
+	roots := g.Roots()
+	if cmd.Args().Present() {
+		id, err := syntax.Parse(cmd.Args().First())
+		if err != nil {
+			return err
+		}
+		key, err := resolveNode(g, id, serverInfo)
+		if err != nil {
+			return err
+		}
+		roots = []string{key}
+	}
+
+	if cmd.Bool(flagJsonOutput.Name) {
+		nodes := make([]treeNode, 0, len(roots))
+		for _, root := range roots {
+			nodes = append(nodes, treeJson(g, root, nil, map[string]bool{}))
+		}
+		tools.PrintAsJson(nodes)
+		return nil
+	}
+
+	switch cmd.String(flagGraphFormat.Name) {
+	case graphFormatDot:
+		fmt.Println(graphDot(g.Subgraph(roots...)))
+	case graphFormatMermaid:
+		fmt.Println(graphMermaid(g.Subgraph(roots...)))
+	default:
+		if len(roots) == 0 {
+			fmt.Println(tools.Dim("(No packages installed)"))
+			return nil
+		}
+		shown := make(map[string]bool)
+		for _, root := range roots {
+			fmt.Println(tools.Bold(nodeLabel(g, root)))
+			printTree(g, root, "", shown, map[string]bool{root: true})
+		}
+	}
+	return nil
+}
+
+// printTree prints the dependencies of the node below it. A subtree already
+// printed is only referred to with (*), and a dependency on a package up the
+// branch is marked as a cycle rather than followed.
+func printTree(g *dependency.Graph, key, indent string, shown, branch map[string]bool) {
+	shown[key] = true
+	edges := g.Dependencies(key)
+	for i, e := range edges {
+		last := i == len(edges)-1
+		line := indent + tools.Ternary(last, "└── ", "├── ") + treeEdgeText(g, e)
+		switch {
+		case branch[e.To]:
+			fmt.Println(line + " " + tools.Yellow("(cycle)"))
+			continue
+		case shown[e.To] && len(g.Dependencies(e.To)) != 0:
+			fmt.Println(line + " " + tools.Dim("(*)"))
+			continue
+		}
+		fmt.Println(line)
+		branch[e.To] = true
+		printTree(g, e.To, indent+tools.Ternary(last, "    ", "│   "), shown, branch)
+		delete(branch, e.To)
+	}
+}
+
+// treeEdgeText shows the dependency as the package it leads to, the
+// constraint, and what is wrong with it, if anything.
+func treeEdgeText(g *dependency.Graph, e dependency.Edge) string {
+	text := nodeLabel(g, e.To)
+	if e.Unsatisfied() {
+		text = tools.Red(text)
+	}
+	var notes []string
+	if c := edgeLabel(e); c != "" {
+		notes = append(notes, "requires "+c)
+	}
+	if e.Status != dependency.StatusSatisfied {
+		notes = append(
+			notes,
+			tools.Ternary(e.Unsatisfied(), tools.Red, tools.Dim)(e.Status.String()),
+		)
+	}
+	if !e.Mandatory {
+		notes = append(notes, tools.Dim("optional"))
+	}
+	if len(notes) == 0 {
+		return text
+	}
+	return text + " " + tools.Dim("(") + strings.Join(notes, tools.Dim(", ")) + tools.Dim(")")
+}
+
+func treeJson(g *dependency.Graph, key string, edge *dependency.Edge, branch map[string]bool) treeNode {
+	n := g.Nodes[key]
+	node := treeNode{Id: key}
+	if n.Installed {
+		node.Version = n.Id.Version.String()
+	}
+	if edge != nil {
+		node.Constraint = edgeLabel(*edge)
+		node.Optional = !edge.Mandatory
+		node.Status = edge.Status.String()
+	}
+	if branch[key] {
+		node.Cycle = true
+		return node
+	}
+	branch[key] = true
+	for _, e := range g.Dependencies(key) {
+		node.Dependencies = append(node.Dependencies, treeJson(g, e.To, &e, branch))
+	}
+	delete(branch, key)
+	return node
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"lucy/dependency"
+	"lucy/logger"
+	"lucy/probe"
+	"lucy/syntax"
+	"lucy/tools"
+
+	"github.com/urfave/cli/v3"
+)
+
+var subcmdWhy = &cli.Command{
+	Name:      "why",
+	Usage:     "Show why a package is installed or required",
+	ArgsUsage: "PACKAGE",
+	Description: "Every chain of installed packages leading to the package is " +
+		"listed. Optional dependencies are shown with a dotted arrow, and " +
+		"packages whose dependency is not met are marked.",
+	Flags: []cli.Flag{
+		flagGraphFormat,
+		flagJsonOutput,
+		flagNoStyle,
+	},
+	Action: tools.Decorate(
+		actionWhy,
+		decoratorGlobalFlags,
+		decoratorHelpAndExitOnNoArg,
+		decoratorLogAndExitOnError,
+	),
+}
+
+// whyStep is a package on a path in the JSON output of `lucy why`. The edge
+// it is reached through is flattened into it, the first step has none.
+type whyStep struct {
+	Id         string `json:"id"`
+	Version    string `json:"version,omitempty"`
+	Constraint string `json:"constraint,omitempty"`
+	Optional   bool   `json:"optional,omitempty"`
+	Status     string `json:"status,omitempty"`
+}
+
+var actionWhy cli.ActionFunc = func(
+	_ context.Context,
+	cmd *cli.Command,
+) error {
+	id, err := syntax.Parse(cmd.Args().First())
+	if err != nil {
+		return err
+	}
+	serverInfo := probe.ServerInfo()
+	g := dependency.NewGraph(serverInfo)
+	key, err := resolveNode(g, id, serverInfo)
+	if err != nil {
+		return err
+	}
+	paths := g.Paths(key)
+
+	if cmd.Bool(flagJsonOutput.Name) {
+		out := make([][]whyStep, 0, len(paths))
+		for _, path := range paths {
+			out = append(out, whyJson(g, path))
+		}
+		tools.PrintAsJson(out)
+		return nil
+	}
+
+	var edges []dependency.Edge
+	for _, path := range paths {
+		edges = append(edges, path...)
+	}
+	switch cmd.String(flagGraphFormat.Name) {
+	case graphFormatDot:
+		fmt.Println(graphDot(g.EdgesOf(edges)))
+	case graphFormatMermaid:
+		fmt.Println(graphMermaid(g.EdgesOf(edges)))
+	default:
+		if len(paths) == 0 {
+			logger.ShowInfo(key + " is not required by any installed package")
+			return nil
+		}
+		for _, path := range paths {
+			fmt.Println(whyText(g, path))
+		}
+	}
+	return nil
+}
+
+// whyText shows the path on a single line, from the package nothing depends
+// on to the one asked about.
+func whyText(g *dependency.Graph, path []dependency.Edge) string {
+	var b strings.Builder
+	b.WriteString(nodeLabel(g, path[0].From))
+	for _, e := range path {
+		b.WriteString(tools.Ternary(e.Mandatory, " → ", tools.Dim(" ⇢ ")))
+		label := nodeLabel(g, e.To)
+		if c := edgeLabel(e); c != "" {
+			label += " " + tools.Dim("("+c+")")
+		}
+		if e.Unsatisfied() {
+			label = tools.Red(nodeLabel(g, e.To)) + " " + tools.Red("["+e.Status.String()+"]")
+			if c := edgeLabel(e); c != "" {
+				label += " " + tools.Dim("(requires "+c+")")
+			}
+		}
+		b.WriteString(label)
+	}
+	return b.String()
+}
+
+func whyJson(g *dependency.Graph, path []dependency.Edge) []whyStep {
+	step := func(key string) whyStep {
+		s := whyStep{Id: key}
+		if n := g.Nodes[key]; n.Installed {
+			s.Version = n.Id.Version.String()
+		}
+		return s
+	}
+	steps := []whyStep{step(path[0].From)}
+	for _, e := range path {
+		s := step(e.To)
+		s.Constraint = edgeLabel(e)
+		s.Optional = !e.Mandatory
+		s.Status = e.Status.String()
+		steps = append(steps, s)
+	}
+	return steps
+}
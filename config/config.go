@@ -0,0 +1,351 @@
+// Package config resolves Lucy's settings from several layers. From the lowest
+// to the highest precedence, they are the defaults in the schema, the user
+// config file, the config file of the server, the environment variables, and
+// finally the flags of the command line.
+//
+// All values are strings at the interface, typed accessors parse them by the
+// Kind of their key. Values are validated when they are loaded or set, so the
+// accessors never fail.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"lucy/global"
+	"lucy/logger"
+	"lucy/tools"
+	"lucy/util"
+)
+
+type Layer uint8
+
+const (
+	LayerDefault Layer = iota
+	LayerUser
+	LayerServer
+	LayerEnv
+	LayerFlag
+)
+
+func (l Layer) String() string {
+	switch l {
+	case LayerDefault:
+		return "default"
+	case LayerUser:
+		return "user"
+	case LayerServer:
+		return "server"
+	case LayerEnv:
+		return "env"
+	case LayerFlag:
+		return "flag"
+	default:
+		return "unknown"
+	}
+}
+
+// Value is a resolved value of a key, with where it came from.
+type Value struct {
+	Key    *Key
+	Value  string
+	Layer  Layer
+	Origin string // the file, environment variable or flag
+}
+
+var (
+	ErrorUnknownKey   = errors.New("unknown key")
+	ErrorInvalidValue = errors.New("invalid value")
+)
+
+// Error locates a bad entry, so that the user can find and fix it.
+type Error struct {
+	Origin string
+	Key    string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Origin, e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+var (
+	values   = map[string]Value{}
+	valuesMu sync.RWMutex
+)
+
+func init() {
+	for _, key := range Schema {
+		values[key.Name] = Value{Key: key, Value: key.Default, Layer: LayerDefault}
+	}
+}
+
+// UserFile is the config file shared by all servers of the user.
+func UserFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return path.Join(dir, global.ProgramName, "config.json")
+}
+
+// ServerFile is the config file of the server in the working directory.
+func ServerFile() string {
+	return util.ConfigFile
+}
+
+// Load reads the files and the environment. Invalid entries are skipped and
+// returned as a joined error of *Error, the rest are still applied.
+func Load() error {
+	var errs []error
+	for _, layer := range []struct {
+		layer Layer
+		file  string
+	}{{LayerUser, UserFile()}, {LayerServer, ServerFile()}} {
+		entries, err := readFile(layer.file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, name := range sortedKeys(entries) {
+			errs = append(errs, apply(name, entries[name], layer.layer, layer.file))
+		}
+	}
+	for _, key := range Schema {
+		for _, env := range append([]string{key.Env()}, key.EnvAliases...) {
+			if v, ok := os.LookupEnv(env); ok {
+				errs = append(errs, apply(key.Name, v, LayerEnv, env))
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetFlag applies a value from the command line.
+func SetFlag(name string, value string, flag string) error {
+	return apply(name, value, LayerFlag, "--"+flag)
+}
+
+func apply(name string, value string, layer Layer, origin string) error {
+	key, ok := Lookup(name)
+	if !ok {
+		return &Error{Origin: origin, Key: name, Err: ErrorUnknownKey}
+	}
+	if err := key.Validate(value); err != nil {
+		return &Error{
+			Origin: origin,
+			Key:    name,
+			Err:    fmt.Errorf("%w %q, %v", ErrorInvalidValue, value, err),
+		}
+	}
+	if key.Secret {
+		logger.AddSecret(value)
+	}
+	valuesMu.Lock()
+	defer valuesMu.Unlock()
+	values[name] = Value{Key: key, Value: value, Layer: layer, Origin: origin}
+	return nil
+}
+
+// Get returns the resolved value of a key in the schema. It panics on an
+// unknown key, as that is a programming error.
+func Get(name string) Value {
+	valuesMu.RLock()
+	defer valuesMu.RUnlock()
+	v, ok := values[name]
+	if !ok {
+		panic("config: unknown key " + name)
+	}
+	return v
+}
+
+// All returns the resolved values in the order of the schema.
+func All() []Value {
+	all := make([]Value, 0, len(Schema))
+	for _, key := range Schema {
+		all = append(all, Get(key.Name))
+	}
+	return all
+}
+
+func String(name string) string { return Get(name).Value }
+
+func Bool(name string) bool {
+	b, _ := strconv.ParseBool(Get(name).Value)
+	return b
+}
+
+func Int(name string) int {
+	i, _ := strconv.Atoi(Get(name).Value)
+	return i
+}
+
+func Duration(name string) time.Duration {
+	d, _ := time.ParseDuration(Get(name).Value)
+	return d
+}
+
+func Size(name string) int64 {
+	s, _ := ParseSize(Get(name).Value)
+	return s
+}
+
+func List(name string) []string { return parseList(Get(name).Value) }
+
+func Map(name string) map[string]string {
+	m, _ := parseMap(Get(name).Value)
+	return m
+}
+
+// Set writes the value of a key to the file. The value is validated first, and
+// stored with the JSON type of its kind.
+func Set(file string, name string, value string) error {
+	key, ok := Lookup(name)
+	if !ok {
+		return &Error{Origin: file, Key: name, Err: ErrorUnknownKey}
+	}
+	if err := key.Validate(value); err != nil {
+		return &Error{
+			Origin: file,
+			Key:    name,
+			Err:    fmt.Errorf("%w %q, %v", ErrorInvalidValue, value, err),
+		}
+	}
+	entries, err := readRawFile(file)
+	if err != nil {
+		return err
+	}
+	entries[name] = encode(key, value)
+	return writeFile(file, entries)
+}
+
+// Unset removes a key from the file. Unknown keys can be removed as well, so
+// that a stale entry can be cleaned up.
+func Unset(file string, name string) error {
+	entries, err := readRawFile(file)
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[name]; !ok {
+		return nil
+	}
+	delete(entries, name)
+	return writeFile(file, entries)
+}
+
+// readRawFile reads the entries of a file as they are. A missing file has no
+// entries.
+func readRawFile(file string) (map[string]json.RawMessage, error) {
+	entries := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: malformed config file: %w", file, err)
+	}
+	return entries, nil
+}
+
+// readFile reads the entries of a file as strings, so that they are validated
+// the same way as those from the environment.
+func readFile(file string) (map[string]string, error) {
+	raw, err := readRawFile(file)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]string, len(raw))
+	for name, data := range raw {
+		entries[name] = decode(data)
+	}
+	return entries, nil
+}
+
+func writeFile(file string, entries map[string]json.RawMessage) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	// The file may hold tokens, so keep it private
+	return os.WriteFile(file, append(data, '\n'), 0o600)
+}
+
+// decode flattens a JSON value to the string form. Arrays become lists and
+// objects become maps.
+func decode(data json.RawMessage) string {
+	// Numbers are kept as written, rather than formatted back from a float64,
+	// e.g., 30000000 as 3e+07
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return string(data)
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]any:
+		pairs := make([]string, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			pairs = append(pairs, k+"="+fmt.Sprint(v[k]))
+		}
+		return strings.Join(pairs, ",")
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func encode(key *Key, value string) json.RawMessage {
+	var v any = value
+	switch key.Kind {
+	case KindBool:
+		v, _ = strconv.ParseBool(value)
+	case KindInt:
+		v, _ = strconv.Atoi(value)
+	case KindList:
+		v = tools.Ternary(parseList(value) == nil, []string{}, parseList(value))
+	case KindMap:
+		v, _ = parseMap(value)
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the type of the value of a key. Values are always exchanged as
+// strings on the command line and in environment variables; Kind decides how
+// they are validated and parsed.
+type Kind uint8
+
+const (
+	KindString Kind = iota
+	KindBool
+	KindInt
+	KindDuration
+	KindSize // a byte size, e.g., 30MB or 2GiB
+	KindList // comma separated strings
+	KindMap  // comma separated key=value pairs
+	KindEnum
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindBool:
+		return "bool"
+	case KindInt:
+		return "int"
+	case KindDuration:
+		return "duration"
+	case KindSize:
+		return "size"
+	case KindList:
+		return "list"
+	case KindMap:
+		return "map"
+	case KindEnum:
+		return "enum"
+	default:
+		return "unknown"
+	}
+}
+
+// Key is an entry of the schema.
+type Key struct {
+	Name    string // dotted path, e.g., source.default
+	Kind    Kind
+	Usage   string
+	Default string
+	Enum    []string // allowed values of a KindEnum
+
+	// Secret values are masked when listed, and redacted from the log.
+	Secret bool
+
+	// EnvAliases are conventional variables read when Env is not set, e.g.,
+	// GITHUB_TOKEN, which CI systems usually provide.
+	EnvAliases []string
+
+	// validate checks a value after it is parsed by its kind, it is optional.
+	validate func(string) error
+}
+
+// Env is the environment variable that sets the key, e.g., LUCY_SOURCE_DEFAULT.
+func (k *Key) Env() string {
+	return "LUCY_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(k.Name))
+}
+
+func (k *Key) Validate(value string) error {
+	var err error
+	switch k.Kind {
+	case KindBool:
+		_, err = strconv.ParseBool(value)
+	case KindInt:
+		_, err = strconv.Atoi(value)
+	case KindDuration:
+		_, err = time.ParseDuration(value)
+	case KindSize:
+		_, err = ParseSize(value)
+	case KindMap:
+		_, err = parseMap(value)
+	case KindEnum:
+		if !slices.Contains(k.Enum, value) {
+			err = fmt.Errorf("must be one of %s", strings.Join(k.Enum, ", "))
+		}
+	}
+	if err == nil && k.validate != nil {
+		err = k.validate(value)
+	}
+	return err
+}
+
+const (
+	KeySourcePriority     = "source.priority"
+	KeySourceDefault      = "source.default"
+	KeyModrinthToken      = "api.modrinth-token"
+	KeyGithubToken        = "api.github-token"
+	KeyCurseforgeKey      = "api.curseforge-key"
+	KeyMirrors            = "mirrors"
+	KeyHttpProxy          = "http.proxy"
+	KeyHttpConnectTimeout = "http.connect-timeout"
+	KeyHttpReadTimeout    = "http.read-timeout"
+	KeyHttpCaFile         = "http.ca-file"
+	KeyHttpInsecure       = "http.insecure"
+	KeyCacheNetworkSize   = "cache.network-size"
+	KeyCachePackageSize   = "cache.package-size"
+	KeyPrerelease         = "prerelease"
+	KeyInteractive        = "interactive"
+	KeyOffline            = "offline"
+)
+
+// Prerelease policies
+const (
+	PrereleaseExclude = "exclude"
+	PrereleaseInclude = "include"
+)
+
+// Interactive modes
+const (
+	InteractiveAuto  = "auto"
+	InteractiveNever = "never"
+)
+
+var knownSources = []string{"modrinth", "mcdr"}
+
+// Schema lists every key Lucy understands. Keys not in the schema are reported
+// as unknown wherever they appear.
+var Schema = []*Key{
+	{
+		Name:    KeySourcePriority,
+		Kind:    KindList,
+		Usage:   "Order of sources to look packages up from",
+		Default: "modrinth,mcdr",
+		validate: func(s string) error {
+			for _, src := range parseList(s) {
+				if !slices.Contains(knownSources, src) {
+					return fmt.Errorf("unknown source %s", src)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Name:    KeySourceDefault,
+		Kind:    KindEnum,
+		Usage:   "Source used when --source is not given",
+		Default: "auto",
+		Enum:    append([]string{"auto"}, knownSources...),
+	},
+	{
+		Name:       KeyModrinthToken,
+		Kind:       KindString,
+		Usage:      "Modrinth personal access token, e.g., to install unlisted or draft versions",
+		Secret:     true,
+		EnvAliases: []string{"MODRINTH_TOKEN"},
+	},
+	{
+		Name:       KeyGithubToken,
+		Kind:       KindString,
+		Usage:      "GitHub token, raises the API rate limit",
+		Secret:     true,
+		EnvAliases: []string{"GITHUB_TOKEN"},
+	},
+	{
+		Name:   KeyCurseforgeKey,
+		Kind:   KindString,
+		Usage:  "CurseForge API key",
+		Secret: true,
+	},
+	{
+		Name:  KeyMirrors,
+		Kind:  KindMap,
+		Usage: "Per-host url rewrites, e.g., api.modrinth.com=https://mirror.example.com",
+		validate: func(s string) error {
+			m, _ := parseMap(s)
+			for host, base := range m {
+				if u, err := url.Parse(base); err != nil || u.Scheme == "" || u.Host == "" {
+					return fmt.Errorf("invalid mirror for %s: %s", host, base)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Name:  KeyHttpProxy,
+		Kind:  KindString,
+		Usage: "Proxy for all requests (http, https or socks5)",
+		validate: func(s string) error {
+			if s == "" {
+				return nil
+			}
+			u, err := url.Parse(s)
+			if err != nil || !slices.Contains([]string{"http", "https", "socks5", "socks5h"}, u.Scheme) {
+				return errors.New("must be an http, https or socks5 url")
+			}
+			return nil
+		},
+	},
+	{
+		Name:    KeyHttpConnectTimeout,
+		Kind:    KindDuration,
+		Usage:   "Timeout of connecting to a server",
+		Default: "10s",
+	},
+	{
+		Name:    KeyHttpReadTimeout,
+		Kind:    KindDuration,
+		Usage:   "Timeout of waiting for a response",
+		Default: "30s",
+	},
+	{
+		Name:  KeyHttpCaFile,
+		Kind:  KindString,
+		Usage: "PEM file of additional root certificates",
+	},
+	{
+		Name:    KeyHttpInsecure,
+		Kind:    KindBool,
+		Usage:   "Skip TLS verification, do not use unless you know why",
+		Default: "false",
+	},
+	{
+		Name:    KeyCacheNetworkSize,
+		Kind:    KindSize,
+		Usage:   "Size limit of the network cache",
+		Default: "30MB",
+	},
+	{
+		Name:    KeyCachePackageSize,
+		Kind:    KindSize,
+		Usage:   "Size limit of the shared package store",
+		Default: "2GB",
+	},
+	{
+		Name:    KeyPrerelease,
+		Kind:    KindEnum,
+		Usage:   "Whether prerelease versions are offered",
+		Default: PrereleaseExclude,
+		Enum:    []string{PrereleaseExclude, PrereleaseInclude},
+	},
+	{
+		Name:    KeyInteractive,
+		Kind:    KindEnum,
+		Usage:   "Whether to open interactive prompts when input is ambiguous",
+		Default: InteractiveAuto,
+		Enum:    []string{InteractiveAuto, InteractiveNever},
+	},
+	{
+		Name:    KeyOffline,
+		Kind:    KindBool,
+		Usage:   "Answer only from the network cache and the package store, never the network",
+		Default: "false",
+	},
+}
+
+// Lookup returns the key in the schema.
+func Lookup(name string) (*Key, bool) {
+	i := slices.IndexFunc(Schema, func(k *Key) bool { return k.Name == name })
+	if i < 0 {
+		return nil, false
+	}
+	return Schema[i], true
+}
+
+// ParseSize parses a byte size with an optional unit. Decimal (KB, MB, GB) and
+// binary (KiB, MiB, GiB) units are both accepted.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9},
+		{"B", 1},
+	}
+	factor := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(strings.ToUpper(s), strings.ToUpper(u.suffix)) {
+			s = strings.TrimSpace(s[:len(s)-len(u.suffix)])
+			factor = u.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+func parseList(s string) (list []string) {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseMap(s string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, pair := range parseList(s) {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected key=value, got %s", pair)
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m, nil
+}
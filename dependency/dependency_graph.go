@@ -0,0 +1,231 @@
+package dependency
+
+import (
+	"slices"
+	"strings"
+
+	"lucy/types"
+)
+
+// Graph is the dependency graph of the packages installed in a server. Its
+// edges come from the dependencies the packages declare. A dependency that is
+// not installed is still a node, so that it can be reported.
+//
+// Dependencies on the game and the platforms are left out, they are met by
+// the server rather than by packages. So are inverse dependencies, which are
+// incompatibilities rather than requirements, see Check for them.
+type Graph struct {
+	Nodes map[string]*Node // by Key
+	Edges []Edge
+}
+
+type Node struct {
+	Id        types.PackageId // with the installed version
+	Installed bool
+}
+
+// Edge is a dependency of From on To.
+type Edge struct {
+	From       string
+	To         string
+	Constraint types.VersionConstraintExpression
+	Mandatory  bool
+	Status     Status
+}
+
+// Key identifies a node. MCDR plugins and mods are told apart, the platforms
+// of mods are not, as the same mod is often published for several loaders.
+func Key(id types.PackageId) string {
+	if id.Platform == types.Mcdr {
+		return "mcdr/" + id.Name.String()
+	}
+	return id.Name.String()
+}
+
+// IsPlatformId tells whether the dependency is on the game, a platform, or
+// the runtime rather than on a package.
+func IsPlatformId(name types.ProjectName) bool {
+	return slices.Contains(gameIds, name) ||
+		slices.Contains(uncheckedIds, name) ||
+		loaderIds[name] != ""
+}
+
+func NewGraph(server types.ServerInfo) *Graph {
+	g := &Graph{Nodes: make(map[string]*Node)}
+	for _, p := range server.Packages {
+		g.Nodes[Key(p.Id)] = &Node{Id: p.Id, Installed: true}
+	}
+	for _, p := range server.Packages {
+		if p.Dependencies == nil {
+			continue
+		}
+		for _, d := range p.Dependencies.Value {
+			to := Key(d.Id)
+			// Some packages list themselves, which is met by definition
+			if d.Inverse || IsPlatformId(d.Id.Name) || to == Key(p.Id) {
+				continue
+			}
+			if _, ok := g.Nodes[to]; !ok {
+				id := d.Id
+				id.Version = types.NoVersion
+				g.Nodes[to] = &Node{Id: id}
+			}
+			status, _ := Check(d, server)
+			g.Edges = append(
+				g.Edges, Edge{
+					From:       Key(p.Id),
+					To:         to,
+					Constraint: d.Constraint,
+					Mandatory:  d.Mandatory,
+					Status:     status,
+				},
+			)
+		}
+	}
+	slices.SortFunc(
+		g.Edges,
+		func(a, b Edge) int {
+			if c := strings.Compare(a.From, b.From); c != 0 {
+				return c
+			}
+			return strings.Compare(a.To, b.To)
+		},
+	)
+	return g
+}
+
+// Dependencies returns the edges from the node.
+func (g *Graph) Dependencies(key string) []Edge {
+	var edges []Edge
+	for _, e := range g.Edges {
+		if e.From == key {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// Dependents returns the edges to the node.
+func (g *Graph) Dependents(key string) []Edge {
+	var edges []Edge
+	for _, e := range g.Edges {
+		if e.To == key {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// Unsatisfied tells whether a dependency on the node is not met, i.e., it is
+// missing or installed in a version outside the constraint.
+func (g *Graph) Unsatisfied(key string) bool {
+	return slices.ContainsFunc(
+		g.Dependents(key),
+		func(e Edge) bool { return e.Unsatisfied() },
+	)
+}
+
+func (e Edge) Unsatisfied() bool {
+	return e.Status == StatusMissing || e.Status == StatusMismatch
+}
+
+// Roots returns the keys of the installed packages no other package depends
+// on, i.e., those installed for their own sake, sorted. Packages only depended
+// on within a cycle would be out of reach from them, so the first package of
+// each such cycle is a root as well.
+func (g *Graph) Roots() []string {
+	var roots, rest []string
+	for key, n := range g.Nodes {
+		if !n.Installed {
+			continue
+		}
+		if len(g.Dependents(key)) == 0 {
+			roots = append(roots, key)
+		} else {
+			rest = append(rest, key)
+		}
+	}
+	slices.Sort(roots)
+	slices.Sort(rest)
+	reached := g.Subgraph(roots...).Nodes
+	for _, key := range rest {
+		if _, ok := reached[key]; !ok {
+			roots = append(roots, key)
+			for k, n := range g.Subgraph(key).Nodes {
+				reached[k] = n
+			}
+		}
+	}
+	return roots
+}
+
+// Paths returns every path of edges that leads to the node from a package
+// nothing depends on. Packages in a dependency cycle have no such package
+// above them, so a path also starts where going further up would revisit a
+// node of the path.
+func (g *Graph) Paths(key string) [][]Edge {
+	var paths [][]Edge
+	var walk func(key string, path []Edge, visited map[string]bool)
+	walk = func(key string, path []Edge, visited map[string]bool) {
+		visited[key] = true
+		defer delete(visited, key)
+		var up []Edge
+		for _, e := range g.Dependents(key) {
+			if !visited[e.From] {
+				up = append(up, e)
+			}
+		}
+		if len(up) == 0 {
+			if len(path) != 0 {
+				paths = append(paths, slices.Clone(path))
+			}
+			return
+		}
+		for _, e := range up {
+			walk(e.From, append([]Edge{e}, path...), visited)
+		}
+	}
+	walk(key, nil, map[string]bool{})
+	return paths
+}
+
+// Subgraph returns the nodes reachable from the roots, and the edges among
+// them.
+func (g *Graph) Subgraph(roots ...string) *Graph {
+	sub := &Graph{Nodes: make(map[string]*Node)}
+	queue := slices.Clone(roots)
+	for len(queue) != 0 {
+		key := queue[0]
+		queue = queue[1:]
+		if _, ok := sub.Nodes[key]; ok {
+			continue
+		}
+		n, ok := g.Nodes[key]
+		if !ok {
+			continue
+		}
+		sub.Nodes[key] = n
+		for _, e := range g.Dependencies(key) {
+			sub.Edges = append(sub.Edges, e)
+			queue = append(queue, e.To)
+		}
+	}
+	return sub
+}
+
+// EdgesOf returns a graph of only the edges, and the nodes they connect.
+func (g *Graph) EdgesOf(edges []Edge) *Graph {
+	sub := &Graph{Nodes: make(map[string]*Node)}
+	for _, e := range edges {
+		if slices.ContainsFunc(
+			sub.Edges,
+			func(o Edge) bool { return o.From == e.From && o.To == e.To },
+		) {
+			continue
+		}
+		sub.Edges = append(sub.Edges, e)
+		sub.Nodes[e.From] = g.Nodes[e.From]
+		sub.Nodes[e.To] = g.Nodes[e.To]
+	}
+	return sub
+}
@@ -0,0 +1,172 @@
+package dependency
+
+import (
+	"slices"
+
+	"lucy/types"
+)
+
+// Status tells whether a dependency is met by a server.
+type Status uint8
+
+const (
+	StatusSatisfied Status = iota
+	StatusMissing          // mandatory, but not installed
+	StatusAbsent           // optional, and not installed
+	StatusMismatch         // installed in a version outside the constraint
+	StatusUnknown          // cannot be checked, e.g., the java version
+	StatusConflict         // an incompatibility, installed in a version inside the constraint
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusSatisfied:
+		return "satisfied"
+	case StatusMissing:
+		return "missing"
+	case StatusAbsent:
+		return "not installed"
+	case StatusMismatch:
+		return "version mismatch"
+	case StatusConflict:
+		return "conflict"
+	default:
+		return "unknown"
+	}
+}
+
+// Dependencies on the game and the platforms themselves are declared in
+// package metadata as well. Their versions come from the executable rather
+// than from installed packages.
+var (
+	gameIds   = []types.ProjectName{"minecraft"}
+	loaderIds = map[types.ProjectName]types.Platform{
+		"fabricloader": types.Fabric,
+		"forge":        types.Forge,
+		"neoforge":     types.Neoforge,
+	}
+	uncheckedIds = []types.ProjectName{"java", "mcdreforged"}
+)
+
+// SchemeOf returns the version scheme the package is versioned in. The game
+// has its own, Forge and NeoForge follow Maven, and everything else semver.
+func SchemeOf(id types.PackageId) types.VersionScheme {
+	switch {
+	case slices.Contains(gameIds, id.Name):
+		return types.MinecraftRelease
+	case id.Platform == types.Forge || id.Platform == types.Neoforge:
+		return types.Maven
+	default:
+		return types.Semver
+	}
+}
+
+// Check tells whether the server meets the dependency, and returns the version
+// that is installed, if any. An inverse dependency is satisfied unless it is
+// installed in a version inside its constraint, which is a conflict.
+func Check(
+	dep types.Dependency,
+	server types.ServerInfo,
+) (status Status, installed types.RawVersion) {
+	status, installed = check(dep, server)
+	if !dep.Inverse {
+		return status, installed
+	}
+	switch status {
+	case StatusSatisfied:
+		return StatusConflict, installed
+	case StatusUnknown:
+		return StatusUnknown, installed
+	default:
+		return StatusSatisfied, installed
+	}
+}
+
+// check is Check regardless of whether the dependency is inverse.
+func check(
+	dep types.Dependency,
+	server types.ServerInfo,
+) (status Status, installed types.RawVersion) {
+	name := dep.Id.Name
+	switch {
+	case slices.Contains(uncheckedIds, name):
+		return StatusUnknown, types.UnknownVersion
+	case slices.Contains(gameIds, name):
+		if server.Executable == nil {
+			return StatusUnknown, types.UnknownVersion
+		}
+		installed = server.Executable.GameVersion
+		return compare(dep, installed, SchemeOf(dep.Id)), installed
+	case loaderIds[name] != "":
+		if server.Executable == nil || server.Executable.ModLoader != loaderIds[name] {
+			return absence(dep), types.NoVersion
+		}
+		installed = server.Executable.LoaderVersion
+		return compare(dep, installed, SchemeOf(dep.Id)), installed
+	}
+
+	for _, p := range server.Packages {
+		if p.Id.Name != name || (p.Id.Platform == types.Mcdr) != (dep.Id.Platform == types.Mcdr) {
+			continue
+		}
+		return compare(dep, p.Id.Version, SchemeOf(dep.Id)), p.Id.Version
+	}
+	return absence(dep), types.NoVersion
+}
+
+func absence(dep types.Dependency) Status {
+	if dep.Mandatory {
+		return StatusMissing
+	}
+	return StatusAbsent
+}
+
+// compare checks the installed version against the constraint. A version that
+// cannot be parsed only satisfies an empty constraint.
+func compare(dep types.Dependency, installed types.RawVersion, scheme types.VersionScheme) Status {
+	if len(dep.Constraint) == 0 {
+		return StatusSatisfied
+	}
+	v := withReleaseOrder(Parse(installed, scheme))
+	if v.Scheme == types.Invalid {
+		return StatusUnknown
+	}
+	dep.Constraint = withReleaseOrders(dep.Constraint)
+	if incomparable(v, dep.Constraint) {
+		return StatusUnknown
+	}
+	if dep.Satisfy(dep.Id, v) {
+		return StatusSatisfied
+	}
+	return StatusMismatch
+}
+
+// incomparable tells whether the constraint refers to a version the installed
+// one cannot be compared with, e.g., one that could not be parsed.
+func incomparable(v types.ComparableVersion, exps types.VersionConstraintExpression) bool {
+	for _, and := range exps {
+		for _, c := range and {
+			if c.Operator == types.OpNone {
+				continue
+			}
+			if c.Value.Scheme == types.Invalid || !v.Comparable(c.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withReleaseOrders returns a copy of the constraint, with the Order of the
+// versions of the game filled in.
+func withReleaseOrders(exps types.VersionConstraintExpression) types.VersionConstraintExpression {
+	ordered := make(types.VersionConstraintExpression, len(exps))
+	for i, and := range exps {
+		ordered[i] = make([]types.VersionConstraint, len(and))
+		for j, c := range and {
+			c.Value = withReleaseOrder(c.Value)
+			ordered[i][j] = c
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,43 @@
+package dependency
+
+import (
+	"strconv"
+	"strings"
+
+	"lucy/types"
+)
+
+// parseMaven parses a version of a Forge or NeoForge mod. Maven accepts any
+// string as a version, here it must at least start with a number. The leading
+// numbers are the components, and the rest is kept as it is in Prerelease,
+// e.g., 1.0-beta-2 is 1.0.0 followed by -beta-2.
+func parseMaven(s string) (v types.ComparableVersion) {
+	end := strings.IndexFunc(s, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+	if end == -1 {
+		end = len(s)
+	}
+	numbers, rest := s[:end], s[end:]
+	// A trailing dot belongs to the rest, e.g., 1.0.Final
+	if trimmed := strings.TrimRight(numbers, "."); trimmed != numbers {
+		rest = numbers[len(trimmed):] + rest
+		numbers = trimmed
+	}
+	var components []uint64
+	for _, token := range strings.Split(numbers, ".") {
+		n, err := strconv.ParseUint(token, 10, 64)
+		if err != nil {
+			return types.InvalidVersion
+		}
+		components = append(components, n)
+	}
+	for len(components) < 3 {
+		components = append(components, 0)
+	}
+	v.Scheme = types.Maven
+	v.Major, v.Minor, v.Patch = components[0], components[1], components[2]
+	if len(components) > 3 {
+		v.Extra = components[3:]
+	}
+	v.Prerelease = rest
+	return v
+}
@@ -0,0 +1,155 @@
+package dependency
+
+import (
+	"regexp"
+	"strconv"
+	"sync"
+
+	"lucy/logger"
+	"lucy/types"
+)
+
+// Shapes of the versions of the game, see
+// https://minecraft.wiki/w/Version_formats
+var (
+	// 1.20.5, 1.20.5-pre3, 1.21-rc1
+	minecraftRelease = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?(?:-(pre|rc)(\d+))?$`)
+	// 1.14 Pre-Release 2, named so until 1.14.4
+	minecraftOldPrerelease = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))? Pre-Release (\d+)$`)
+	// 24w14a, and April Fools snapshots like 24w14potato or 23w13a_or_b
+	minecraftSnapshot = regexp.MustCompile(`^(\d{2})w(\d{2})([a-z][a-z_]*)$`)
+	// a1.2.6, b1.7.3, c0.30_01c, c0.0.11a
+	minecraftLegacy = regexp.MustCompile(`^([abc])(\d+)\.(\d+)(?:\.(\d+))?([a-z_\d]*)$`)
+	// rd-132211, in-20091223-2, inf-20100618
+	minecraftLegacyBuild = regexp.MustCompile(`^(rd|in|inf)-([\d-]+)$`)
+	// The semver Fabric normalizes versions to, and mods declare dependencies
+	// on, e.g., 1.20.5-alpha.24.14.a for 24w14a, 1.20.5-beta.3 for
+	// 1.20.5-pre3, and 1.20.5-rc.1 for 1.20.5-rc1
+	minecraftFabricSnapshot   = regexp.MustCompile(`^\d+\.\d+(?:\.\d+)?-alpha\.(\d{2})\.(\d{2})\.([a-z][a-z_]*)$`)
+	minecraftFabricPrerelease = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?-(beta|rc)\.(\d+)$`)
+)
+
+// parseMinecraft parses any version of the game by its shape. April Fools
+// versions that follow no shape, e.g., "3D Shareware v1.34", are invalid.
+func parseMinecraft(s string) (v types.ComparableVersion) {
+	atoi := func(s string) uint64 {
+		n, _ := strconv.ParseUint(s, 10, 64)
+		return n
+	}
+	if m := minecraftRelease.FindStringSubmatch(s); m != nil {
+		v.Scheme = types.MinecraftRelease
+		v.Major, v.Minor, v.Patch = atoi(m[1]), atoi(m[2]), atoi(m[3])
+		v.Prerelease = m[4] + m[5]
+		return v
+	}
+	if m := minecraftOldPrerelease.FindStringSubmatch(s); m != nil {
+		v.Scheme = types.MinecraftRelease
+		v.Major, v.Minor, v.Patch = atoi(m[1]), atoi(m[2]), atoi(m[3])
+		v.Prerelease = "pre" + m[4]
+		return v
+	}
+	if m := minecraftFabricPrerelease.FindStringSubmatch(s); m != nil {
+		v.Scheme = types.MinecraftRelease
+		v.Major, v.Minor, v.Patch = atoi(m[1]), atoi(m[2]), atoi(m[3])
+		v.Prerelease = map[string]string{"beta": "pre", "rc": "rc"}[m[4]] + m[5]
+		return v
+	}
+	if m := minecraftFabricSnapshot.FindStringSubmatch(s); m != nil {
+		s = m[1] + "w" + m[2] + m[3]
+	}
+	if m := minecraftSnapshot.FindStringSubmatch(s); m != nil {
+		v.Scheme = types.MinecraftSnapshot
+		v.Major, v.Minor = atoi(m[1]), atoi(m[2])
+		v.Patch = uint64(m[3][0])
+		if len(m[3]) > 1 {
+			v.Prerelease = m[3]
+		}
+		return v
+	}
+	if m := minecraftLegacy.FindStringSubmatch(s); m != nil {
+		v.Scheme = types.MinecraftLegacy
+		v.Prerelease = m[1]
+		v.Major, v.Minor, v.Patch = atoi(m[2]), atoi(m[3]), atoi(m[4])
+		v.Build = m[5]
+		return v
+	}
+	if m := minecraftLegacyBuild.FindStringSubmatch(s); m != nil {
+		v.Scheme = types.MinecraftLegacy
+		v.Prerelease, v.Build = m[1], m[2]
+		return v
+	}
+	return types.InvalidVersion
+}
+
+// releaseIds lists the ids of all versions of the game, oldest first. It is
+// handed in by the caller, see SetReleaseOrder, so that this package does not
+// fetch the version manifest itself.
+var releaseIds func() ([]string, error)
+
+// SetReleaseOrder hands the package where to get the ids of all versions of the
+// game from, oldest first, e.g., the version manifest. It is only called once
+// a version of the game is compared.
+func SetReleaseOrder(ids func() ([]string, error)) {
+	releaseIds = ids
+}
+
+// releaseOrder maps each version in the release order, formatted by
+// ComparableVersion.String, to its position counted from the oldest. It is
+// nil if the order is not available, then versions are only compared by their
+// shape, and snapshots and releases by a fallback ordering.
+var releaseOrder = sync.OnceValue(
+	func() map[string]uint32 {
+		if releaseIds == nil {
+			return nil
+		}
+		ids, err := releaseIds()
+		if err != nil {
+			logger.Info("cannot order versions of the game by the manifest: " + err.Error())
+			return nil
+		}
+		order, cycles := releaseOrderOf(ids)
+		// Versions without an Order are then placed the way the manifest
+		// orders them too
+		types.SetMinecraftCycles(cycles)
+		return order
+	},
+)
+
+// releaseOrderOf maps the ids, oldest first, to their positions, see
+// releaseOrder. The cycles are the releases with the last snapshot before
+// each, see types.MinecraftCycle.
+func releaseOrderOf(ids []string) (order map[string]uint32, cycles []types.MinecraftCycle) {
+	order = make(map[string]uint32, len(ids))
+	var snapshot *[3]uint64 // the last one since the last cycle
+	for i, id := range ids {
+		v := parseMinecraft(id)
+		switch v.Scheme {
+		case types.Invalid:
+			continue
+		case types.MinecraftSnapshot:
+			snapshot = &[3]uint64{v.Major, v.Minor, v.Patch}
+		case types.MinecraftRelease:
+			if snapshot != nil {
+				cycles = append(
+					cycles, types.MinecraftCycle{
+						Release:  [3]uint64{v.Major, v.Minor, v.Patch},
+						Snapshot: *snapshot,
+					},
+				)
+				snapshot = nil
+			}
+		}
+		order[v.String()] = uint32(i + 1)
+	}
+	return order, cycles
+}
+
+// withReleaseOrder fills in the Order of a version of the game. A snapshot can
+// only be compared with a release this way.
+func withReleaseOrder(v types.ComparableVersion) types.ComparableVersion {
+	if !v.Scheme.IsMinecraft() {
+		return v
+	}
+	v.Order = releaseOrder()[v.String()]
+	return v
+}
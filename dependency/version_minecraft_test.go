@@ -0,0 +1,113 @@
+package dependency
+
+import (
+	"testing"
+
+	"lucy/types"
+)
+
+func TestParseMinecraft(t *testing.T) {
+	tests := []struct {
+		raw    string
+		scheme types.VersionScheme
+		want   string // formatted by String
+	}{
+		{"1.20.1", types.MinecraftRelease, "1.20.1"},
+		{"1.21", types.MinecraftRelease, "1.21"},
+		{"1.20.5-pre3", types.MinecraftRelease, "1.20.5-pre3"},
+		{"1.21-rc1", types.MinecraftRelease, "1.21-rc1"},
+		{"1.14 Pre-Release 2", types.MinecraftRelease, "1.14-pre2"},
+		{"1.14.4 Pre-Release 1", types.MinecraftRelease, "1.14.4-pre1"},
+		{"24w14a", types.MinecraftSnapshot, "24w14a"},
+		{"24w14potato", types.MinecraftSnapshot, "24w14potato"},
+		{"23w13a_or_b", types.MinecraftSnapshot, "23w13a_or_b"},
+		{"a1.2.6", types.MinecraftLegacy, "a1.2.6"},
+		{"b1.7.3", types.MinecraftLegacy, "b1.7.3"},
+		{"c0.30_01c", types.MinecraftLegacy, "c0.30_01c"},
+		{"c0.0.11a", types.MinecraftLegacy, "c0.0.11a"},
+		{"rd-132211", types.MinecraftLegacy, "rd-132211"},
+		{"in-20091223-2", types.MinecraftLegacy, "in-20091223-2"},
+		{"inf-20100618", types.MinecraftLegacy, "inf-20100618"},
+		// As Fabric normalizes them
+		{"1.20.5-alpha.24.14.a", types.MinecraftSnapshot, "24w14a"},
+		{"1.20.5-beta.3", types.MinecraftRelease, "1.20.5-pre3"},
+		{"1.20.5-rc.1", types.MinecraftRelease, "1.20.5-rc1"},
+		{"3D Shareware v1.34", types.Invalid, "invalid"},
+		{"1.20.x", types.Invalid, "invalid"},
+	}
+	for _, tt := range tests {
+		v := Parse(types.RawVersion(tt.raw), types.MinecraftRelease)
+		if v.Scheme != tt.scheme || v.String() != tt.want {
+			t.Errorf("Parse(%q) = %s of scheme %d, want %s of scheme %d", tt.raw, v, v.Scheme, tt.want, tt.scheme)
+		}
+	}
+}
+
+// testManifest is a part of the version manifest, oldest first. April Fools
+// snapshots are left out, as they do not follow the order of their shape.
+var testManifest = []string{
+	"rd-132211", "c0.0.11a", "a1.2.6", "b1.7.3", "1.0",
+	"1.20.4", "24w14a", "1.20.5-pre1", "1.20.5-rc1", "1.20.5", "1.20.6",
+	"24w18a", "24w21b", "1.21-pre1", "1.21-rc1", "1.21", "1.21.1",
+}
+
+func TestReleaseOrderOf(t *testing.T) {
+	order, cycles := releaseOrderOf(append(testManifest, "3D Shareware v1.34"))
+	for i, id := range testManifest {
+		key := parseMinecraft(id).String()
+		if order[key] != uint32(i+1) {
+			t.Errorf("%s is at %d, want %d", id, order[key], i+1)
+		}
+	}
+	want := []types.MinecraftCycle{
+		{Release: [3]uint64{1, 20, 5}, Snapshot: [3]uint64{24, 14, 'a'}},
+		{Release: [3]uint64{1, 21, 0}, Snapshot: [3]uint64{24, 21, 'b'}},
+	}
+	if len(cycles) != len(want) || cycles[0] != want[0] || cycles[1] != want[1] {
+		t.Errorf("got cycles %v, want %v", cycles, want)
+	}
+}
+
+// Versions with an Order, and those without, e.g., the bounds Next builds,
+// must be ordered alike, or ranges over them are not consistent.
+func TestReleaseOrderAgreesWithShape(t *testing.T) {
+	order, cycles := releaseOrderOf(testManifest)
+	types.SetMinecraftCycles(cycles)
+	versions := make([]types.ComparableVersion, len(testManifest))
+	for i, id := range testManifest {
+		versions[i] = parseMinecraft(id)
+		versions[i].Order = order[versions[i].String()]
+	}
+	for i, a := range versions {
+		for j, b := range versions {
+			unordered := b
+			unordered.Order = 0
+			if got, want := a.Lt(unordered), i < j; got != want {
+				t.Errorf("%s < %s is %v by shape, but %v by the manifest", a, b, got, want)
+			}
+		}
+	}
+
+	// A bound built by Next, which is only ordered by shape. Snapshots for 1.21
+	// come before its lowest pre-release.
+	next := versions[slicesIndex(testManifest, "1.20.6")].Next(2)
+	for _, id := range []string{"1.20.5-pre1", "1.20.6", "24w14a", "24w18a", "24w21b"} {
+		if v := versions[slicesIndex(testManifest, id)]; !v.Lt(next) {
+			t.Errorf("%s is not below %s", v, next)
+		}
+	}
+	for _, id := range []string{"1.21-pre1", "1.21", "1.21.1"} {
+		if v := versions[slicesIndex(testManifest, id)]; !v.Gte(next) {
+			t.Errorf("%s is not at or above %s", v, next)
+		}
+	}
+}
+
+func slicesIndex(s []string, v string) int {
+	for i := range s {
+		if s[i] == v {
+			return i
+		}
+	}
+	panic(v + " is not in the manifest")
+}
@@ -12,7 +12,8 @@ import (
 // If the raw version is one of the special constants (which should be inferred
 // before passing to this function), it returns InvalidVersion.
 //
-// It will attempt each type of version parsing, in order of specificity.
+// It will attempt each type of version parsing, in order of specificity. The
+// Minecraft schemes are interchangeable, the shape of the version decides.
 //
 // If the label is not compatible with the version, return a semantic version
 // that is labeled as Raw and contains the raw version as it is. This is to
@@ -29,111 +30,42 @@ func Parse(
 	switch scheme {
 	case types.Semver:
 		return parseSemver(string(raw))
-	case types.MinecraftRelease:
-		return parseMinecraftRelease(string(raw))
-	case types.MinecraftSnapshot:
-		return parseMinecraftSnapshot(string(raw))
+	case types.MinecraftRelease, types.MinecraftSnapshot, types.MinecraftLegacy:
+		return parseMinecraft(string(raw))
+	case types.Maven:
+		return parseMaven(string(raw))
 	default:
 		return types.InvalidVersion
 	}
 }
 
+// parseSemver parses a SemVer 2.0 version, e.g., 1.2.3-beta.1+build. It is
+// lenient in the number of components, as Fabric is, so that 1.20 and
+// 1.2.3.4 are valid as well. The prerelease starts at the first '-', and the
+// build at the first '+', e.g., 1.20.1-47.2.20 is 1.20.1 with prerelease
+// 47.2.20, and 0.92.2+1.20.1 is 0.92.2 with build 1.20.1.
 func parseSemver(s string) (v types.ComparableVersion) {
-	return operatorPlus(s)
-}
-
-// These two are equivalent, for now.
-func parseMinecraftRelease(s string) (v types.ComparableVersion) {
-	return parseSemver(s)
-}
-
-func operatorPlus(s string) (v types.ComparableVersion) {
-	tokens := strings.Split(s, "+")
-	if len(tokens) >= 2 {
-		s = strings.Join(tokens[:len(tokens)-1], "")
-	}
-	v = operatorDash(s)
-	if v == types.InvalidVersion {
-		return v
-	}
-	if len(tokens) >= 2 {
-		v.Build = tokens[len(tokens)-1]
-	}
-	return v
-}
-
-func operatorDash(s string) (v types.ComparableVersion) {
-	tokens := strings.Split(s, "-")
-	if len(tokens) >= 2 {
-		s = strings.Join(tokens[:len(tokens)-1], "")
-	}
-	v = operatorDot(s)
-	if v == types.InvalidVersion {
-		return v
-	}
-	if len(tokens) >= 2 {
-		v.Prerelease = tokens[len(tokens)-1]
-	}
-	return v
-}
-
-func operatorDot(s string) (v types.ComparableVersion) {
-	tokens := strings.Split(s, ".")
-	if len(tokens) >= 2 {
-		major, err := strconv.Atoi(tokens[0])
-		if err != nil {
-			return types.InvalidVersion
-		}
-		minor, err := strconv.Atoi(tokens[1])
-		if err != nil {
-			return types.InvalidVersion
-		}
-		v.Major = uint16(major)
-		v.Minor = uint16(minor)
+	s, build, hasBuild := strings.Cut(s, "+")
+	s, prerelease, hasPrerelease := strings.Cut(s, "-")
+	if (hasBuild && build == "") || (hasPrerelease && prerelease == "") {
+		return types.InvalidVersion
 	}
-	if len(tokens) == 3 {
-		patch, err := strconv.Atoi(tokens[2])
+	var components []uint64
+	for _, token := range strings.Split(s, ".") {
+		n, err := strconv.ParseUint(token, 10, 64)
 		if err != nil {
 			return types.InvalidVersion
 		}
-		v.Patch = uint16(patch)
+		components = append(components, n)
 	}
-	return v
-}
-
-func parseMinecraftSnapshot(s string) types.ComparableVersion {
-	return operatorInWeekIndex(s)
-}
-
-func operatorWeek(s string) (v types.ComparableVersion) {
-	tokens := strings.Split(s, "w")
-	if len(tokens) != 2 {
-		return types.InvalidVersion
+	for len(components) < 3 {
+		components = append(components, 0)
 	}
-	major, err := strconv.Atoi(tokens[0])
-	if err != nil {
-		return types.InvalidVersion
-	}
-	minor, err := strconv.Atoi(tokens[1])
-	if err != nil {
-		return types.InvalidVersion
-	}
-	v.Major = uint16(major)
-	v.Minor = uint16(minor)
-	return v
-}
-
-func operatorInWeekIndex(s string) (v types.ComparableVersion) {
-	tokens := s[len(s)-1]
-	v = operatorWeek(s[:len(s)-1])
-	if v == types.InvalidVersion {
-		return v
-	}
-	switch tokens {
-	case 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h':
-		v.Patch = uint16(tokens)
-	default:
-		return types.InvalidVersion
+	v.Scheme = types.Semver
+	v.Major, v.Minor, v.Patch = components[0], components[1], components[2]
+	if len(components) > 3 {
+		v.Extra = components[3:]
 	}
+	v.Prerelease, v.Build = prerelease, build
 	return v
 }
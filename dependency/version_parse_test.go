@@ -0,0 +1,30 @@
+package dependency
+
+import (
+	"testing"
+
+	"lucy/types"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // formatted by String, or invalid
+	}{
+		{"1.2.3", "1.2.3"},
+		{"1.20", "1.20.0"},
+		{"1.2.3.4", "1.2.3.4"},
+		{"1.2.3-beta.1+build.5", "1.2.3-beta.1+build.5"},
+		{"0.92.2+1.20.1", "0.92.2+1.20.1"},
+		{"1.20.1-47.2.20", "1.20.1-47.2.20"},
+		{"1.2.3-", "invalid"},
+		{"1.2.3+", "invalid"},
+		{"1.x", "invalid"},
+		{"v1.2.3", "invalid"},
+	}
+	for _, tt := range tests {
+		if v := Parse(types.RawVersion(tt.raw), types.Semver); v.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.raw, v, tt.want)
+		}
+	}
+}
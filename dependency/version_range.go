@@ -0,0 +1,374 @@
+package dependency
+
+import (
+	"fmt"
+	"strings"
+
+	"lucy/types"
+)
+
+// RangeError is a version range that cannot be parsed. Offset is the byte
+// offset of the offending character in Range.
+type RangeError struct {
+	Range   string
+	Offset  int
+	Message string
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf(
+		"invalid version range %q: %s at position %d",
+		e.Range,
+		e.Message,
+		e.Offset+1,
+	)
+}
+
+// ParseRange parses an npm-style version range, see
+// https://github.com/npm/node-semver#ranges
+//
+// Ranges separated by "||" are ORed, and the comparators within a range,
+// separated by spaces, are ANDed. Supported comparators are:
+//   - 1.2.3 or =1.2.3, exactly the version
+//   - >1.2.3, >=1.2.3, <1.2.3, <=1.2.3
+//   - ~1.2.3, >=1.2.3 <1.3.0, patch updates only
+//   - ^1.2.3, >=1.2.3 <2.0.0, updates not changing the leftmost non-zero
+//     component, e.g., ^0.5 is >=0.5.0 <0.6.0
+//   - 1.2.3 - 2.3.4, a hyphen range, both ends included
+//
+// A version may be partial, or have x, X or * as components, which then
+// match any. For example, 1.2, 1.2.x and ~1.2 are all >=1.2.0 <1.3.0, and *
+// is any version.
+//
+// Versions are parsed in the scheme given. Those that are not shaped like
+// semver, e.g., snapshots of the game, are taken as a whole. The result is
+// nil if any version satisfies the range.
+func ParseRange(s string, scheme types.VersionScheme) (types.VersionConstraintExpression, error) {
+	var exps types.VersionConstraintExpression
+	offset := 0
+	for _, or := range strings.Split(s, "||") {
+		and, err := parseRangeAnd(s, offset, or, scheme)
+		if err != nil {
+			return nil, err
+		}
+		exps = append(exps, and)
+		offset += len(or) + len("||")
+	}
+	if exps.Simplify() == nil {
+		return nil, nil
+	}
+	return exps, nil
+}
+
+// rangeToken is a word of a range, and where it starts in the whole range.
+type rangeToken struct {
+	text   string
+	offset int
+}
+
+func parseRangeAnd(
+	whole string,
+	offset int,
+	s string,
+	scheme types.VersionScheme,
+) (and []types.VersionConstraint, err error) {
+	var tokens []rangeToken
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+		end := strings.IndexAny(s[i:], " \t")
+		if end == -1 {
+			end = len(s)
+		} else {
+			end += i
+		}
+		token := rangeToken{text: s[i:end], offset: offset + i}
+		// An operator may be separated from its version, e.g., ">= 1.2"
+		if n := len(tokens); n != 0 && strings.Trim(tokens[n-1].text, "<>=^~") == "" {
+			tokens[n-1].text += token.text
+		} else {
+			tokens = append(tokens, token)
+		}
+		i = end
+	}
+	if len(tokens) == 0 {
+		if strings.TrimSpace(whole) == "" {
+			return nil, nil
+		}
+		// Point at the "||" before the empty range
+		return nil, &RangeError{whole, max(offset-len("||"), 0), "empty range"}
+	}
+
+	fail := func(t rangeToken, at int, message string) error {
+		return &RangeError{whole, t.offset + at, message}
+	}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.text == "-" {
+			return nil, fail(t, 0, "hyphen without a version before it")
+		}
+		// A hyphen range, e.g., 1.2 - 2.3.4
+		if i+1 < len(tokens) && tokens[i+1].text == "-" {
+			if i+2 == len(tokens) {
+				return nil, fail(tokens[i+1], 0, "hyphen without a version after it")
+			}
+			lower, err := parsePartial(t, scheme, fail)
+			if err != nil {
+				return nil, err
+			}
+			upper, err := parsePartial(tokens[i+2], scheme, fail)
+			if err != nil {
+				return nil, err
+			}
+			and = append(and, lower.atLeast()...)
+			and = append(and, upper.atMost()...)
+			i += 2
+			continue
+		}
+		constraints, err := parseComparator(t, scheme, fail)
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, constraints...)
+	}
+	return and, nil
+}
+
+func parseComparator(
+	t rangeToken,
+	scheme types.VersionScheme,
+	fail func(rangeToken, int, string) error,
+) ([]types.VersionConstraint, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(t.text, prefix) {
+			op = prefix
+			break
+		}
+	}
+	version := rangeToken{text: t.text[len(op):], offset: t.offset + len(op)}
+	if version.text == "" {
+		return nil, fail(t, len(t.text), "missing version after "+op)
+	}
+	if c := version.text[0]; strings.IndexByte("<>=^~", c) != -1 {
+		return nil, fail(version, 0, fmt.Sprintf("unexpected %q", c))
+	}
+	p, err := parsePartial(version, scheme, fail)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "", "=":
+		return p.exactly(), nil
+	case ">":
+		return p.above(), nil
+	case ">=":
+		return p.atLeast(), nil
+	case "<":
+		return p.below(), nil
+	case "<=":
+		return p.atMost(), nil
+	case "~":
+		return p.tilde(), nil
+	default: // "^"
+		return p.caret(), nil
+	}
+}
+
+// partial is a version, of which only the first n components are given. A
+// version with a prerelease or build is always complete.
+type partial struct {
+	v types.ComparableVersion
+	n int
+	// complete is true if the version is given in full, then the comparators
+	// keep their plain meaning
+	complete bool
+}
+
+// parsePartial parses a possibly partial version, e.g., 1.2, 1.x or *.
+func parsePartial(
+	t rangeToken,
+	scheme types.VersionScheme,
+	fail func(rangeToken, int, string) error,
+) (p partial, err error) {
+	s := strings.TrimPrefix(t.text, "v")
+	skipped := len(t.text) - len(s)
+
+	// Scan the numeric components, up to the prerelease or build
+	var numbers []string
+	wildcard := false
+	i := 0
+	for {
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		switch {
+		case i > start:
+			if wildcard {
+				return p, fail(t, skipped+start, "version after a wildcard")
+			}
+			numbers = append(numbers, s[start:i])
+		case i < len(s) && strings.IndexByte("xX*", s[i]) != -1:
+			wildcard = true
+			i++
+		default:
+			return parseWhole(t, scheme, fail, skipped+i, "expected a number or x")
+		}
+		if i == len(s) || s[i] != '.' {
+			break
+		}
+		i++
+	}
+	rest := s[i:]
+	if rest != "" && rest[0] != '-' && rest[0] != '+' {
+		return parseWhole(t, scheme, fail, skipped+i, fmt.Sprintf("unexpected %q", rest[0]))
+	}
+	if rest != "" && wildcard {
+		return p, fail(t, skipped+i, "prerelease or build after a wildcard")
+	}
+
+	p.n = len(numbers)
+	p.complete = rest != "" || (!wildcard && p.n >= 3)
+	if p.n == 0 {
+		return p, nil
+	}
+	p.v = Parse(types.RawVersion(strings.Join(numbers, ".")+rest), scheme)
+	if p.v.Scheme == types.Invalid {
+		return p, fail(t, 0, "invalid version")
+	}
+	return p, nil
+}
+
+// parseWhole takes a version not shaped like semver as a whole, e.g., a
+// snapshot of the game. If it is not valid in the scheme either, the error
+// points at where it stopped looking like semver.
+func parseWhole(
+	t rangeToken,
+	scheme types.VersionScheme,
+	fail func(rangeToken, int, string) error,
+	at int,
+	message string,
+) (p partial, err error) {
+	v := Parse(types.RawVersion(t.text), scheme)
+	if scheme == types.Semver || v.Scheme == types.Invalid {
+		return p, fail(t, at, message)
+	}
+	return partial{v: v, n: len(v.Extra) + 3, complete: true}, nil
+}
+
+func (p partial) any() bool {
+	return p.n == 0
+}
+
+// next is the lowest version above those the partial version stands for.
+func (p partial) next() types.ComparableVersion {
+	return p.v.Next(p.n)
+}
+
+func constraint(op types.VersionOperator, v types.ComparableVersion) []types.VersionConstraint {
+	return []types.VersionConstraint{{Value: v, Operator: op}}
+}
+
+var noVersion = []types.VersionConstraint{{Operator: types.OpNone}}
+
+func (p partial) exactly() []types.VersionConstraint {
+	switch {
+	case p.any():
+		return nil
+	case p.complete:
+		return constraint(types.OpEq, p.v)
+	}
+	return append(constraint(types.OpGte, p.v), constraint(types.OpLt, p.next())...)
+}
+
+func (p partial) above() []types.VersionConstraint {
+	switch {
+	case p.any():
+		return noVersion
+	case p.complete:
+		return constraint(types.OpGt, p.v)
+	}
+	return constraint(types.OpGte, p.next())
+}
+
+func (p partial) atLeast() []types.VersionConstraint {
+	if p.any() {
+		return nil
+	}
+	return constraint(types.OpGte, p.v)
+}
+
+func (p partial) below() []types.VersionConstraint {
+	switch {
+	case p.any():
+		return noVersion
+	case p.complete:
+		return constraint(types.OpLt, p.v)
+	}
+	// <1.2 excludes the prereleases of 1.2.0 too
+	v := p.v
+	v.Prerelease = v.Scheme.LowestPrerelease()
+	return constraint(types.OpLt, v)
+}
+
+func (p partial) atMost() []types.VersionConstraint {
+	switch {
+	case p.any():
+		return nil
+	case p.complete:
+		return constraint(types.OpLte, p.v)
+	}
+	return constraint(types.OpLt, p.next())
+}
+
+// tilde allows patch updates if the minor version is given, and minor
+// updates otherwise.
+func (p partial) tilde() []types.VersionConstraint {
+	if p.any() {
+		return nil
+	}
+	return append(constraint(types.OpGte, p.v), constraint(types.OpLt, p.v.Next(min(p.n, 2)))...)
+}
+
+// caret allows updates that do not change the leftmost non-zero component
+// given, or the last one given if all are zero, e.g., ^0.0 is <0.1.0.
+func (p partial) caret() []types.VersionConstraint {
+	if p.any() {
+		return nil
+	}
+	components := append([]uint64{p.v.Major, p.v.Minor, p.v.Patch}, p.v.Extra...)
+	n := p.n
+	for i := range p.n {
+		if components[i] != 0 {
+			n = i + 1
+			break
+		}
+	}
+	return append(constraint(types.OpGte, p.v), constraint(types.OpLt, p.v.Next(n))...)
+}
+
+// Highest returns the index of the highest version satisfying the constraint,
+// or -1 if none does. Versions that cannot be parsed in the scheme are left
+// out.
+func Highest(
+	versions []types.RawVersion,
+	exps types.VersionConstraintExpression,
+	scheme types.VersionScheme,
+) int {
+	exps = withReleaseOrders(exps)
+	best := -1
+	var bestVersion types.ComparableVersion
+	for i, raw := range versions {
+		v := withReleaseOrder(Parse(raw, scheme))
+		if v.Scheme == types.Invalid || incomparable(v, exps) || !exps.Contains(v) {
+			continue
+		}
+		if best == -1 || v.Gt(bestVersion) {
+			best, bestVersion = i, v
+		}
+	}
+	return best
+}
@@ -0,0 +1,128 @@
+package dependency
+
+import (
+	"errors"
+	"testing"
+
+	"lucy/types"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string // formatted by String
+	}{
+		// Comparators
+		{"1.2.3", "=1.2.3"},
+		{"=1.2.3", "=1.2.3"},
+		{">1.2.3", ">1.2.3"},
+		{">= 1.2.3", ">=1.2.3"},
+		{"<1.2.3", "<1.2.3"},
+		{"<=1.2.3", "<=1.2.3"},
+		{"v1.2.3", "=1.2.3"},
+		{"1.2.3-beta.1", "=1.2.3-beta.1"},
+		{">=1.4.100 <1.5", ">=1.4.100 <1.5.0-0"},
+		// Partial versions and x-ranges
+		{"*", "*"},
+		{"", "*"},
+		{"x", "*"},
+		{"1", ">=1.0.0 <2.0.0-0"},
+		{"1.2", ">=1.2.0 <1.3.0-0"},
+		{"1.2.x", ">=1.2.0 <1.3.0-0"},
+		{"1.X", ">=1.0.0 <2.0.0-0"},
+		{"1.*.*", ">=1.0.0 <2.0.0-0"},
+		{">1.2", ">=1.3.0-0"},
+		{"<1.2", "<1.2.0-0"},
+		{"<=1.2", "<1.3.0-0"},
+		{">*", "none"},
+		// Tilde ranges
+		{"~1.2.3", ">=1.2.3 <1.3.0-0"},
+		{"~1.2", ">=1.2.0 <1.3.0-0"},
+		{"~1", ">=1.0.0 <2.0.0-0"},
+		{"~0.2.3", ">=0.2.3 <0.3.0-0"},
+		{"~1.2.3-beta.2", ">=1.2.3-beta.2 <1.3.0-0"},
+		// Caret ranges
+		{"^1.2.3", ">=1.2.3 <2.0.0-0"},
+		{"^0.2.3", ">=0.2.3 <0.3.0-0"},
+		{"^0.0.3", ">=0.0.3 <0.0.4-0"},
+		{"^0.5", ">=0.5.0 <0.6.0-0"},
+		{"^0.0", ">=0.0.0 <0.1.0-0"},
+		{"^1.x", ">=1.0.0 <2.0.0-0"},
+		{"^1.2.3-beta.2", ">=1.2.3-beta.2 <2.0.0-0"},
+		// Hyphen ranges
+		{"1.2.3 - 2.3.4", ">=1.2.3 <=2.3.4"},
+		{"1.2 - 2.3.4", ">=1.2.0 <=2.3.4"},
+		{"1.2.3 - 2.3", ">=1.2.3 <2.4.0-0"},
+		{"1.2.3 - 2", ">=1.2.3 <3.0.0-0"},
+		// Unions
+		{"1.2.7 || >=1.2.9 <2.0.0", "=1.2.7 || >=1.2.9 <2.0.0"},
+		{"^1.0 || ^2.0", ">=1.0.0 <2.0.0-0 || >=2.0.0 <3.0.0-0"},
+		{"^1.0 || *", "*"},
+	}
+	for _, tt := range tests {
+		got, err := ParseRange(tt.s, types.Semver)
+		if err != nil {
+			t.Errorf("ParseRange(%q) failed: %v", tt.s, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseRange(%q) = %s, want %s", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseRangeMinecraft(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"1.20.x", ">=1.20 <1.21-0"},
+		{">=1.20.5-pre1", ">=1.20.5-pre1"},
+		{">=24w14a <1.21", ">=24w14a <1.21-0"},
+		{">=24w14a <=1.21", ">=24w14a <1.22-0"},
+	}
+	for _, tt := range tests {
+		got, err := ParseRange(tt.s, types.MinecraftRelease)
+		if err != nil {
+			t.Errorf("ParseRange(%q) failed: %v", tt.s, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseRange(%q) = %s, want %s", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseRangeError(t *testing.T) {
+	tests := []struct {
+		s      string
+		offset int
+	}{
+		{">=", 2},
+		{">= ", 2},
+		{"1.2 ||", 4},
+		{"|| 1.2", 0},
+		{"1.2 || || 2.0", 4},
+		{"- 1.2", 0},
+		{"1.2 -", 4},
+		{">=<1.2", 2},
+		{"1.2.3 >=a", 8},
+		{"1.2.y", 4},
+		{"v1.2.y", 5},
+		{"1.x.2", 4},
+		{"1.x-beta", 3},
+		{"1.2.3_4", 5},
+		{"^1.0 || 2.0 - 2.q", 16},
+	}
+	for _, tt := range tests {
+		_, err := ParseRange(tt.s, types.Semver)
+		var rangeErr *RangeError
+		if !errors.As(err, &rangeErr) {
+			t.Errorf("ParseRange(%q) = %v, want a RangeError", tt.s, err)
+			continue
+		}
+		if rangeErr.Offset != tt.offset {
+			t.Errorf("ParseRange(%q) fails at %d, want %d: %v", tt.s, rangeErr.Offset, tt.offset, err)
+		}
+	}
+}
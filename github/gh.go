@@ -1,13 +1,13 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
 
-	"lucy/logger"
-	"lucy/tools"
+	"lucy/util"
 )
 
 // checkGitHubMessage checks if the response data is a GitHub API error message
@@ -21,17 +21,15 @@ func checkGitHubMessage(data []byte) *GhApiMessage {
 	return nil
 }
 
-func GetFileFromGitHub(apiEndpoint string) (
+// GetFileFromGitHub fetches a file through the contents API. Like the other
+// functions here, it sends its requests through r, the requester of the
+// source calling it.
+func GetFileFromGitHub(ctx context.Context, r util.Requester, apiEndpoint string) (
 	err error,
 	msg *GhApiMessage,
 	data []byte,
 ) {
-	resp, err := http.Get(apiEndpoint)
-	if err != nil {
-		return err, nil, nil
-	}
-	defer tools.CloseReader(resp.Body, logger.Warn)
-	data, err = io.ReadAll(resp.Body)
+	data, _, err = r.GetCached(ctx, apiEndpoint)
 	if err != nil {
 		return err, nil, nil
 	}
@@ -46,12 +44,7 @@ func GetFileFromGitHub(apiEndpoint string) (
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrCannotDecode, err), nil, nil
 	}
-	resp, err = http.Get(item.DownloadUrl)
-	if err != nil {
-		return err, nil, nil
-	}
-	defer tools.CloseReader(resp.Body, logger.Warn)
-	data, err = io.ReadAll(resp.Body)
+	data, _, err = r.GetCached(ctx, item.DownloadUrl)
 	if err != nil {
 		return err, nil, nil
 	}
@@ -59,17 +52,12 @@ func GetFileFromGitHub(apiEndpoint string) (
 	return nil, nil, data
 }
 
-func GetDirectoryFromGitHub(apiEndpoint string) (
+func GetDirectoryFromGitHub(ctx context.Context, r util.Requester, apiEndpoint string) (
 	err error,
 	msg *GhApiMessage,
 	items []GhItem,
 ) {
-	resp, err := http.Get(apiEndpoint)
-	if err != nil {
-		return err, nil, nil
-	}
-	defer tools.CloseReader(resp.Body, logger.Warn)
-	data, err := io.ReadAll(resp.Body)
+	data, _, err := r.GetCached(ctx, apiEndpoint)
 	if err != nil {
 		return err, nil, nil
 	}
@@ -86,3 +74,26 @@ func GetDirectoryFromGitHub(apiEndpoint string) (
 	}
 	return nil, nil, res
 }
+
+// GetRawFileFromGitHub fetches a file from raw.githubusercontent.com directly.
+// Unlike the contents API, raw files do not count towards the API rate limit,
+// which makes it suitable for fetching many small files at once.
+//
+// A non-200 response is returned as a message, mirroring the API functions.
+func GetRawFileFromGitHub(ctx context.Context, r util.Requester, rawUrl string) (
+	err error,
+	msg *GhApiMessage,
+	data []byte,
+) {
+	data, status, err := r.GetCached(ctx, rawUrl)
+	if err != nil {
+		return err, nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, &GhApiMessage{
+			Message: strconv.Itoa(status) + " " + http.StatusText(status),
+			Status:  strconv.Itoa(status),
+		}, nil
+	}
+	return nil, nil, data
+}
@@ -4,6 +4,9 @@ import "time"
 
 const ProgramName = "lucy"
 
+// Version is set at build time with -ldflags "-X lucy/global.Version=...".
+var Version = "dev"
+
 const (
 	OneMinute     = 1 * time.Minute
 	TenMinutes    = 10 * time.Minute
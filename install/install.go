@@ -3,3 +3,340 @@ package install
 // This package would be written through dependency injection.
 // The basic idea is writing a script/installer for each package platform,
 // and then executing the script through this package.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"lucy/cache"
+	"lucy/logger"
+	"lucy/tools"
+	"lucy/tui/progress"
+	"lucy/types"
+	"lucy/util"
+)
+
+var (
+	ErrorNoRemote            = errors.New("package has no remote file")
+	ErrorNoMcdr              = errors.New("mcdr not found")
+	ErrorNoModPath           = errors.New("no mod directory found")
+	ErrorUnsupportedPlatform = errors.New("unsupported platform")
+)
+
+// TargetDir returns the directory which a package of the platform should be
+// installed into.
+//
+// TODO: This is a temporary solution, we should have a more robust way to
+// determine the directories, and allow non-standard installation methods.
+func TargetDir(
+	platform types.Platform,
+	serverInfo types.ServerInfo,
+) (dir string, err error) {
+	switch {
+	case platform == types.Mcdr:
+		mcdr := serverInfo.Environments.Mcdr
+		if mcdr == nil || len(mcdr.PluginDirectories) == 0 {
+			return "", ErrorNoMcdr
+		}
+		return mcdr.PluginDirectories[0], nil
+	case platform.IsModding():
+		if len(serverInfo.ModPath) == 0 {
+			return "", ErrorNoModPath
+		}
+		return serverInfo.ModPath[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrorUnsupportedPlatform, platform)
+	}
+}
+
+// Transaction installs a group of packages all-or-nothing.
+//
+// Every file is first downloaded into a staging directory inside its target
+// directory, so that moving it into place is a rename on the same filesystem.
+// Downloads run in parallel and are verified against the hash published by
+// the source. Nothing is moved until all downloads succeeded. If a move fails
+// midway, the files already moved are removed and any file they replaced is
+// restored.
+type Transaction struct {
+	items []transactionItem
+}
+
+type transactionItem struct {
+	pkg types.Package
+	dir string
+	// file and origin are set for a package installed from a local file
+	// rather than downloaded from its remote
+	file   string
+	origin string
+}
+
+func NewTransaction() *Transaction {
+	return &Transaction{}
+}
+
+// Add queues a package to be installed into dir. The package must have its
+// remote resolved.
+func (t *Transaction) Add(p types.Package, dir string) error {
+	if p.Remote == nil {
+		return fmt.Errorf("%w: %s", ErrorNoRemote, p.Id.StringFull())
+	}
+	t.items = append(t.items, transactionItem{pkg: p, dir: dir})
+	return nil
+}
+
+// AddFile queues a package to be installed into dir from a local file. The
+// origin, the local file or the URL it was downloaded from, is recorded once
+// installed, see OriginOf.
+func (t *Transaction) AddFile(p types.Package, file string, origin string, dir string) {
+	t.items = append(t.items, transactionItem{pkg: p, dir: dir, file: file, origin: origin})
+}
+
+func (t *Transaction) Len() int {
+	return len(t.items)
+}
+
+// staged is a file that is downloaded but not yet moved into place.
+type staged struct {
+	id     types.PackageId
+	temp   string
+	dest   string
+	backup string // empty if dest did not exist
+	origin string // empty if installed from a source
+}
+
+// Commit downloads and installs all queued packages. It either installs every
+// package or leaves the target directories as they were.
+func (t *Transaction) Commit() (err error) {
+	stagingDirs := make(map[string]string)
+	defer func() {
+		for _, dir := range stagingDirs {
+			if err := os.RemoveAll(dir); err != nil {
+				logger.Warn(fmt.Errorf("failed to clean up staging directory: %w", err))
+			}
+		}
+	}()
+
+	// Files already in the package store are linked from it, the rest are
+	// downloaded and added to it afterward.
+	results := make([]util.DownloadResult, len(t.items))
+	var tasks []util.DownloadTask
+	var pending []int // index of the item of each task
+	for i, item := range t.items {
+		// Keyed by the cleaned directory, which is what filepath.Dir of a
+		// destination gives back
+		stagingDir, ok := stagingDirs[filepath.Clean(item.dir)]
+		if !ok {
+			if err = os.MkdirAll(item.dir, 0o755); err != nil {
+				return err
+			}
+			stagingDir, err = os.MkdirTemp(item.dir, ".lucy-staging-")
+			if err != nil {
+				return err
+			}
+			stagingDirs[filepath.Clean(item.dir)] = stagingDir
+		}
+		if item.file != "" {
+			results[i] = copyInto(item.file, stagingDir)
+			continue
+		}
+		if r, ok := fromStore(item.pkg, stagingDir); ok {
+			results[i] = r
+			continue
+		}
+		tasks = append(tasks, downloadTask(item.pkg.Remote, stagingDir))
+		pending = append(pending, i)
+	}
+
+	if len(tasks) != 0 {
+		var downloaded []util.DownloadResult
+		if downloaded, err = Download(tasks); err != nil {
+			return err
+		}
+		for j, r := range downloaded {
+			i := pending[j]
+			results[i] = r
+			if r.Err != nil {
+				continue
+			}
+			if _, err := cache.Package.Put(r.Path, t.items[i].pkg.Id.StringFull()); err != nil {
+				logger.Warn(fmt.Errorf("cannot add %s to package store: %w", r.Path, err))
+			}
+		}
+	}
+
+	files := make([]*staged, 0, len(t.items))
+	for i, item := range t.items {
+		if err = results[i].Err; err != nil {
+			return fmt.Errorf("download %s failed: %w", item.pkg.Id.StringFull(), err)
+		}
+		files = append(
+			files, &staged{
+				id:     item.pkg.Id,
+				temp:   results[i].Path,
+				dest:   filepath.Join(item.dir, filepath.Base(results[i].Path)),
+				origin: item.origin,
+			},
+		)
+		logger.Info("staged " + item.pkg.Id.StringFull())
+	}
+
+	var done []*staged
+	for _, f := range files {
+		if err = f.move(stagingDirs[filepath.Dir(f.dest)]); err != nil {
+			rollback(done)
+			return fmt.Errorf("install %s failed: %w", f.id.StringFull(), err)
+		}
+		done = append(done, f)
+		logger.Info("installed " + f.id.StringFull() + " to " + f.dest)
+	}
+	if err := updateOrigins(done); err != nil {
+		logger.ReportWarn(fmt.Errorf("cannot record where the packages were installed from: %w", err))
+	}
+	return nil
+}
+
+// copyInto copies the local file into dir.
+func copyInto(file string, dir string) util.DownloadResult {
+	src, err := os.Open(file)
+	if err != nil {
+		return util.DownloadResult{Err: err}
+	}
+	defer tools.CloseReader(src, logger.Warn)
+	dest, err := tools.CopyFile(src, filepath.Join(dir, filepath.Base(file)))
+	if err != nil {
+		return util.DownloadResult{Err: err}
+	}
+	stat, err := os.Stat(dest.Name())
+	if err != nil {
+		return util.DownloadResult{Err: err}
+	}
+	return util.DownloadResult{Path: dest.Name(), Size: stat.Size()}
+}
+
+// downloadTask is the task downloading the file of the remote into dir, from
+// the mirrors of its host as well.
+func downloadTask(remote *types.PackageRemote, dir string) util.DownloadTask {
+	return util.DownloadTask{
+		Url:        remote.FileUrl,
+		Mirrors:    util.MirrorsOf(remote.FileUrl),
+		Dir:        dir,
+		Filename:   remote.Filename,
+		Hash:       remote.Hash,
+		HashMethod: remote.HashMethod,
+	}
+}
+
+// Download runs the tasks with a progress display. It only fails when the user
+// interrupted it, with progress.ErrInterrupted, failures of single tasks are
+// in their results.
+func Download(tasks []util.DownloadTask) ([]util.DownloadResult, error) {
+	group := progress.NewGroup("Downloading")
+	for i, task := range tasks {
+		group.Queue(i, tools.Ternary(task.Filename != "", task.Filename, task.Url))
+	}
+	downloader := util.NewDownloader()
+	downloader.Reporter = group
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var results []util.DownloadResult
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer group.Close()
+		results = downloader.Download(ctx, tasks)
+	}()
+	err := group.Run()
+	// The display only returns before the downloads finished when the user
+	// interrupted it
+	cancel()
+	<-finished
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// fromStore links the file of the package from the package store into dir.
+// The store is addressed by SHA-512. Packages published with another hash are
+// looked up by their id instead, and checked against that hash once linked.
+func fromStore(p types.Package, dir string) (util.DownloadResult, bool) {
+	remote := p.Remote
+	sum, ok := storedSum(p)
+	if !ok {
+		return util.DownloadResult{}, false
+	}
+	// Named as a download of it would be
+	dest := filepath.Join(dir, downloadTask(remote, dir).Name())
+	method, err := cache.Package.LinkOut(sum, dest)
+	if err != nil {
+		logger.Info(fmt.Errorf("cannot use package store for %s: %w", p.Id.StringFull(), err))
+		return util.DownloadResult{}, false
+	}
+	if remote.HashMethod != types.HashSha512 {
+		if err := util.VerifyFile(dest, remote.Hash, remote.HashMethod); err != nil {
+			logger.Info(fmt.Errorf("ignoring stored %s: %w", p.Id.StringFull(), err))
+			_ = os.Remove(dest)
+			return util.DownloadResult{}, false
+		}
+	}
+	logger.Info("linked " + p.Id.StringFull() + " from package store by " + method)
+	stat, err := os.Stat(dest)
+	if err != nil {
+		return util.DownloadResult{}, false
+	}
+	return util.DownloadResult{Path: dest, Size: stat.Size()}, true
+}
+
+// storedSum returns the SHA-512 of the file of the package if it is in the
+// package store. A file found by the package id is not yet checked against the
+// hash of the remote.
+func storedSum(p types.Package) (sum string, ok bool) {
+	remote := p.Remote
+	sum = remote.Hash
+	if remote.HashMethod != types.HashSha512 {
+		if sum, ok = cache.Package.Lookup(p.Id.StringFull()); !ok || remote.Hash == "" {
+			return "", false
+		}
+	}
+	return sum, cache.Package.Has(sum)
+}
+
+// move moves the staged file into place. An existing destination file is kept
+// in the staging directory as a backup until the transaction completes.
+func (f *staged) move(stagingDir string) error {
+	if _, err := os.Stat(f.dest); err == nil {
+		f.backup = filepath.Join(stagingDir, filepath.Base(f.dest)+".bak")
+		if err := os.Rename(f.dest, f.backup); err != nil {
+			f.backup = ""
+			return err
+		}
+	}
+	if err := os.Rename(f.temp, f.dest); err != nil {
+		f.restore()
+		return err
+	}
+	return nil
+}
+
+func (f *staged) restore() {
+	if f.backup == "" {
+		return
+	}
+	if err := os.Rename(f.backup, f.dest); err != nil {
+		logger.ReportError(fmt.Errorf("failed to restore %s: %w", f.dest, err))
+	}
+}
+
+func rollback(done []*staged) {
+	for i := len(done) - 1; i >= 0; i-- {
+		f := done[i]
+		if err := os.Remove(f.dest); err != nil {
+			logger.ReportError(fmt.Errorf("failed to roll back %s: %w", f.dest, err))
+			continue
+		}
+		f.restore()
+	}
+}
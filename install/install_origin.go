@@ -0,0 +1,87 @@
+package install
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"lucy/util"
+)
+
+// originsFile records where the packages not tracked by any source were
+// installed from, keyed by their installed path.
+const originsFile = util.ProgramPath + "/origins.json"
+
+// Origin is where a package was installed from, when it is a local file or a
+// direct URL rather than a version in a source.
+type Origin struct {
+	// Location is the absolute path of the local file, or the URL
+	Location  string    `json:"location"`
+	Installed time.Time `json:"installed"`
+}
+
+// OriginOf returns the origin of the installed file, if it was installed from
+// a local file or a direct URL.
+func OriginOf(file string) (Origin, bool) {
+	origins, err := readOrigins()
+	if err != nil {
+		return Origin{}, false
+	}
+	o, ok := origins[originKey(file)]
+	return o, ok
+}
+
+func originKey(file string) string {
+	return filepath.ToSlash(filepath.Clean(file))
+}
+
+func readOrigins() (map[string]Origin, error) {
+	origins := make(map[string]Origin)
+	data, err := os.ReadFile(originsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return origins, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &origins); err != nil {
+		return nil, err
+	}
+	return origins, nil
+}
+
+// updateOrigins records the origins of the installed files. A file installed
+// from a source has no origin, and any earlier one is dropped, so that it is
+// tracked by the source again.
+func updateOrigins(files []*staged) error {
+	origins, err := readOrigins()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, f := range files {
+		key := originKey(f.dest)
+		if f.origin == "" {
+			if _, ok := origins[key]; ok {
+				delete(origins, key)
+				changed = true
+			}
+			continue
+		}
+		origins[key] = Origin{Location: f.origin, Installed: time.Now()}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(origins, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(originsFile), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(originsFile, data, 0o644)
+}
@@ -0,0 +1,74 @@
+package install
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"lucy/cache"
+	"lucy/logger"
+	"lucy/types"
+	"lucy/util"
+)
+
+// Prefetch downloads the files of the packages into the package store, so
+// that a Transaction can later install them without the network. Packages
+// whose file is already stored are skipped. It returns how many files were
+// downloaded, and the failures of single packages as a joined error.
+func Prefetch(pkgs []types.Package) (fetched int, err error) {
+	var tasks []util.DownloadTask
+	var pending []types.Package
+	for _, p := range pkgs {
+		if p.Remote == nil {
+			return 0, fmt.Errorf("%w: %s", ErrorNoRemote, p.Id.StringFull())
+		}
+		if _, ok := storedSum(p); ok {
+			logger.Info(p.Id.StringFull() + " is already in package store")
+			continue
+		}
+		pending = append(pending, p)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	dir, err := os.MkdirTemp("", "lucy-prefetch-")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warn(fmt.Errorf("failed to clean up prefetch directory: %w", err))
+		}
+	}()
+	for i, p := range pending {
+		// A directory for each, so that files of the same name from different
+		// packages do not clash before they are stored
+		taskDir := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.Mkdir(taskDir, 0o755); err != nil {
+			return 0, err
+		}
+		tasks = append(tasks, downloadTask(p.Remote, taskDir))
+	}
+	results, err := Download(tasks)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for i, r := range results {
+		id := pending[i].Id.StringFull()
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("download %s failed: %w", id, r.Err))
+			continue
+		}
+		if _, err := cache.Package.Put(r.Path, id); err != nil {
+			errs = append(errs, fmt.Errorf("cannot add %s to package store: %w", id, err))
+			continue
+		}
+		fetched++
+	}
+	return fetched, errors.Join(errs...)
+}
@@ -173,7 +173,7 @@ func DumpHistory() {
 			os.Stderr,
 			timestamp,
 			e.Level.prefix(true),
-			e.Content,
+			redact(e.Content),
 		)
 	}
 }
@@ -9,11 +9,11 @@ import (
 
 func writeToFile(e *entry) {
 	timestamp := e.Time.Format("2006-01-02 15:04:05")
-	_, _ = fmt.Fprintln(LogFile, timestamp, e.Level.prefix(false), e.Content)
+	_, _ = fmt.Fprintln(LogFile, timestamp, e.Level.prefix(false), redact(e.Content))
 }
 
 func writeToConsole(e *entry) {
-	_, _ = fmt.Fprintln(os.Stderr, e.Level.prefix(true), e.Content)
+	_, _ = fmt.Fprintln(os.Stderr, e.Level.prefix(true), redact(e.Content))
 }
 
 func record(e *entry) {
@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secrets in log entries.
+const redacted = "[REDACTED]"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// AddSecret registers a value, e.g., an API token, that must never appear in
+// the log file or on the console. Entries containing it are written with the
+// value replaced.
+func AddSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets = append(secrets, secret)
+}
+
+func redact(content any) any {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	if len(secrets) == 0 {
+		return content
+	}
+	s := fmt.Sprint(content)
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
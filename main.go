@@ -13,6 +13,10 @@ import (
 func main() {
 	defer logger.DumpHistory() // Whether DumpHistory actually does anything depend on the flag.
 	if err := cmd.Cli.Run(context.Background(), os.Args); err != nil {
-		logger.ReportError(err)
+		if !cmd.Reported(err) {
+			logger.ReportError(err)
+		}
+		logger.DumpHistory()
+		os.Exit(1)
 	}
 }
@@ -4,7 +4,8 @@ import (
 	"archive/zip"
 	"fmt"
 	"os"
-	"path"
+	"path/filepath"
+	"strings"
 
 	"lucy/logger"
 	"lucy/tools"
@@ -73,7 +74,7 @@ func Packages(filePath string) (res []types.Package) {
 		return nil
 	}
 
-	switch path.Ext(filePath) {
+	switch PackageExt(filePath) {
 	case ".jar", ".zip":
 		zipReader, err := zip.NewReader(file, stat.Size())
 		if err != nil {
@@ -87,7 +88,7 @@ func Packages(filePath string) (res []types.Package) {
 			res = append(res, result...)
 		}
 	case ".pyz", ".mcdr":
-		McdrPlugin(filePath)
+		res = McdrPlugin(filePath)
 	default:
 		return nil
 	}
@@ -95,6 +96,17 @@ func Packages(filePath string) (res []types.Package) {
 	return
 }
 
+// PackageExt returns the extension of a mod or plugin file, lowercased, e.g.,
+// .jar for Mod.JAR. It is empty if the file is not named like a package.
+func PackageExt(filePath string) string {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	switch ext {
+	case ".jar", ".zip", ".pyz", ".mcdr":
+		return ext
+	}
+	return ""
+}
+
 func McdrPlugin(filePath string) (res []types.Package) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -0,0 +1,25 @@
+package detector
+
+import "testing"
+
+func TestPackageExt(t *testing.T) {
+	tests := []struct {
+		filePath string
+		want     string
+	}{
+		{"mods/sodium-fabric-0.5.8+mc1.20.1.jar", ".jar"},
+		{"Mod.JAR", ".jar"},
+		{"plugins/Plugin.Mcdr", ".mcdr"},
+		{"plugin.pyz", ".pyz"},
+		{"datapack.ZIP", ".zip"},
+		{"sodium", ""},
+		{"fabric-api-0.92.2+1.20.1", ""},
+		{"archive.tar.gz", ""},
+		{"mods.jar/readme", ""},
+	}
+	for _, tt := range tests {
+		if got := PackageExt(tt.filePath); got != tt.want {
+			t.Errorf("PackageExt(%q) = %q, want %q", tt.filePath, got, tt.want)
+		}
+	}
+}
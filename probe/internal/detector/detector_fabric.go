@@ -8,6 +8,7 @@ import (
 	"os"
 	"strings"
 
+	"lucy/dependency"
 	externaltype "lucy/exttype"
 	"lucy/logger"
 	"lucy/syntax"
@@ -268,16 +269,15 @@ func (d *fabricModDetector) buildDependency(
 	inverse bool,
 ) {
 	for k, v := range deps {
+		id := types.PackageId{
+			Platform: types.Fabric,
+			Name:     syntax.ToProjectName(k),
+		}
 		dep := types.Dependency{
-			Id: types.PackageId{
-				Platform: types.Fabric,
-				Name:     syntax.ToProjectName(k),
-			},
-			Constraint: parseFabricVersionRange(v),
+			Id:         id,
+			Constraint: parseFabricVersionRange(v, dependency.SchemeOf(id)),
 			Mandatory:  mandatory,
-		}
-		if inverse {
-			dep.Constraint.Inverse()
+			Inverse:    inverse,
 		}
 		pkg.Dependencies.Value = append(pkg.Dependencies.Value, dep)
 	}
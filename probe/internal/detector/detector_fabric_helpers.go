@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"slices"
 	"strings"
 
 	"lucy/dependency"
@@ -16,7 +17,13 @@ import (
 // Returns [][]VersionConstraint where:
 // - outer array represents OR alternatives
 // - inner array represents AND constraints
-func parseFabricVersionRange(s string) types.VersionConstraintExpression {
+//
+// The versions are parsed in the scheme of the dependency, see
+// dependency.SchemeOf.
+func parseFabricVersionRange(
+	s string,
+	scheme types.VersionScheme,
+) types.VersionConstraintExpression {
 	s = strings.TrimSpace(s)
 	if s == "*" {
 		return nil
@@ -33,10 +40,10 @@ func parseFabricVersionRange(s string) types.VersionConstraintExpression {
 		if strings.Contains(part, ",") {
 			subParts := strings.Split(part, ",")
 			for _, subPart := range subParts {
-				andConstraints = append(andConstraints, parseSingleFabricVersion(subPart))
+				andConstraints = append(andConstraints, parseSingleFabricVersion(subPart, scheme)...)
 			}
 		} else {
-			andConstraints = append(andConstraints, parseSingleFabricVersion(part))
+			andConstraints = append(andConstraints, parseSingleFabricVersion(part, scheme)...)
 		}
 	}
 
@@ -47,25 +54,69 @@ func parseFabricVersionRange(s string) types.VersionConstraintExpression {
 	return nil
 }
 
-func parseSingleFabricVersion(version string) types.VersionConstraint {
+// parseSingleFabricVersion parses a version predicate, which is an operator
+// and a version. Following Fabric, ~ keeps the minor version and ^ keeps the
+// major one, and trailing x, X or * components are wildcards, e.g., 1.20.x
+// is any 1.20 version. A predicate with a wildcard becomes a range.
+func parseSingleFabricVersion(
+	version string,
+	scheme types.VersionScheme,
+) []types.VersionConstraint {
 	version = strings.TrimSpace(version)
 	op := types.OpEq
-	if strings.HasPrefix(version, "<") {
-		op = types.OpLt
-		version = strings.TrimPrefix(version, "<")
-	} else if strings.HasPrefix(version, "<=") {
-		op = types.OpLte
-		version = strings.TrimPrefix(version, "<=")
-	} else if strings.HasPrefix(version, ">") {
-		op = types.OpGt
-		version = strings.TrimPrefix(version, ">")
-	} else if strings.HasPrefix(version, ">=") {
-		op = types.OpGte
-		version = strings.TrimPrefix(version, ">=")
+	// Longer operators first, so that "<=" is not taken for "<"
+	for _, prefix := range []struct {
+		sign string
+		op   types.VersionOperator
+	}{
+		{"<=", types.OpLte},
+		{">=", types.OpGte},
+		{"<", types.OpLt},
+		{">", types.OpGt},
+		{"=", types.OpEq},
+		{"~", types.OpWeakEq},
+		{"^", types.OpWeakGt},
+	} {
+		if after, ok := strings.CutPrefix(version, prefix.sign); ok {
+			op, version = prefix.op, after
+			break
+		}
+	}
+
+	components := strings.Split(version, ".")
+	fixed := len(components)
+	for fixed > 0 && slices.Contains([]string{"x", "X", "*"}, components[fixed-1]) {
+		fixed--
+	}
+	if fixed == len(components) || fixed == 0 {
+		return []types.VersionConstraint{
+			{
+				Value:    dependency.Parse(types.RawVersion(version), scheme),
+				Operator: op,
+			},
+		}
 	}
 
-	return types.VersionConstraint{
-		Value:    dependency.Parse(types.RawVersion(version), types.Semver),
-		Operator: op,
+	// Wildcards are 0 in the lower bound, a comparison with a wildcard
+	// version is one with the range it stands for
+	for i := fixed; i < len(components); i++ {
+		components[i] = "0"
+	}
+	lower := dependency.Parse(types.RawVersion(strings.Join(components, ".")), scheme)
+	upper := lower.Next(fixed)
+	switch op {
+	case types.OpLt:
+		return []types.VersionConstraint{{Value: lower, Operator: types.OpLt}}
+	case types.OpLte:
+		return []types.VersionConstraint{{Value: upper, Operator: types.OpLt}}
+	case types.OpGt:
+		return []types.VersionConstraint{{Value: upper, Operator: types.OpGte}}
+	case types.OpGte:
+		return []types.VersionConstraint{{Value: lower, Operator: types.OpGte}}
+	default:
+		return []types.VersionConstraint{
+			{Value: lower, Operator: types.OpGte},
+			{Value: upper, Operator: types.OpLt},
+		}
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 
+	"lucy/dependency"
 	"lucy/exttype"
 	"lucy/logger"
 	"lucy/syntax"
@@ -153,14 +154,18 @@ func (d *forgeModDetector) Detect(
 				// add manual dependency/conflict management features.
 				deps := modIdentifier.Dependencies[mod.ModID]
 				for _, dep := range deps {
+					id := types.PackageId{
+						Platform: types.Forge,
+						Name:     syntax.ToProjectName(dep.ModID),
+					}
 					p.Dependencies.Value = append(
 						p.Dependencies.Value,
 						types.Dependency{
-							Id: types.PackageId{
-								Platform: types.Forge,
-								Name:     syntax.ToProjectName(dep.ModID),
-							},
-							Constraint: parseMavenVersionRange(dep.VersionRange),
+							Id: id,
+							Constraint: parseMavenVersionRange(
+								dep.VersionRange,
+								dependency.SchemeOf(id),
+							),
 						},
 					)
 				}
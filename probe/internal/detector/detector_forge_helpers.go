@@ -47,117 +47,121 @@ func getForgeModVersion(zip *zip.Reader) types.RawVersion {
 	return types.RawVersion(v)
 }
 
-// parseMavenVersionRange parses Maven version range strings into 2D constraint arrays.
+// parseMavenVersionRange parses a Maven version range, the format of the
+// versionRange of Forge and NeoForge dependencies, see
+// https://maven.apache.org/enforcer/enforcer-rules/versionRanges.html
 //
-// Maven version range format (per Maven POM specification):
-// - [1.0,2.0] - inclusive range (>=1.0 AND <=2.0)
-// - (1.0,2.0) - exclusive range (>1.0 AND <2.0)
-// - [1.0,2.0) - mixed range (>=1.0 AND <2.0)
-// - [1.0] - ex≈act version match (hard requirement)
-// - 1.0 - soft requirement (or >=1.0 in some contexts)
-// - >=1.0, >1.0, <=2.0, <2.0 - single constraint (extension for Forge/NeoForge)
+//   - [1.0,2.0) is a range, a bracket includes the bound and a parenthesis
+//     excludes it. Either bound can be left out, e.g., [47,).
+//   - [1.0] is exactly 1.0.
+//   - Ranges are joined by commas into a union, e.g., (,1.0],[1.2,).
+//   - 1.0 is a soft requirement, which Maven takes as a recommendation only,
+//     so it accepts any version.
 //
-// Returns [][]VersionConstraint where:
-// - outer array represents OR alternatives
-// - inner array represents AND constraints (e.g., range bounds)
+// Each range becomes the AND of its bounds, and the union the OR of them. A
+// malformed range is kept as a constraint on an invalid version, so that it
+// is reported rather than taken as no constraint.
 //
-// Note: This implementation focuses on the most common Maven version range formats
-// used by Forge and NeoForge. Full Maven specification supports comma-separated
-// multiple ranges like "(,1.0],[1.2,)", but current implementation only processes
-// the first range. Operators like >=, >, <=, <, ^, ~ are Forge/NeoForge extensions
-// and may not be standard Maven notation.
+// Operators like >=, >, <=, <, ^, ~ are not Maven, but some mods use them and
+// they are read as a single constraint.
 //
-// Version compatibility:
-// - Standard Maven syntax: All Maven versions
-// - Operator extensions (>=, >, etc.): Primarily used by Forge/NeoForge mods
-func parseMavenVersionRange(interval string) [][]types.VersionConstraint {
-	interval = strings.TrimSpace(interval)
-	if interval == "" || interval == "*" {
+// The versions are parsed in the scheme of the dependency, see
+// dependency.SchemeOf.
+func parseMavenVersionRange(
+	interval string,
+	scheme types.VersionScheme,
+) types.VersionConstraintExpression {
+	interval = strings.ReplaceAll(interval, " ", "")
+	if interval == "" || interval == "*" || strings.EqualFold(interval, "none") {
 		return nil
 	}
-	if strings.EqualFold(interval, "none") {
+	if strings.ContainsAny(interval[:1], "<>=!^~") {
+		return types.VersionConstraintExpression{parseMavenOperator(interval, scheme)}
+	}
+	if interval[0] != '[' && interval[0] != '(' {
 		return nil
 	}
 
-	interval = strings.ReplaceAll(interval, " ", "")
-	// Handle multiple ranges separated by commas (take only first for now)
-	for _, pattern := range []struct {
-		sep    string
-		closer string
-	}{
-		{sep: "],[", closer: "]"},
-		{sep: "),(", closer: ")"},
-		{sep: "],(", closer: "]"},
-		{sep: "),[", closer: ")"},
-	} {
-		if strings.Contains(interval, pattern.sep) {
-			parts := strings.SplitN(interval, pattern.sep, 2)
-			interval = parts[0] + pattern.closer
-			break
+	var exps types.VersionConstraintExpression
+	for rest := interval; rest != ""; {
+		end := strings.IndexAny(rest, "])")
+		if end == -1 || (rest[0] != '[' && rest[0] != '(') {
+			return invalidMavenRange
+		}
+		and, ok := parseMavenRestriction(rest[:end+1], scheme)
+		if !ok {
+			return invalidMavenRange
+		}
+		exps = append(exps, and)
+		rest = rest[end+1:]
+		if rest != "" {
+			var comma bool
+			if rest, comma = strings.CutPrefix(rest, ","); !comma || rest == "" {
+				return invalidMavenRange
+			}
 		}
 	}
+	return exps
+}
 
-	if len(interval) >= 2 {
-		leftBracket := interval[0]
-		rightBracket := interval[len(interval)-1]
-		if (leftBracket == '[' || leftBracket == '(') &&
-			(rightBracket == ']' || rightBracket == ')') {
-			body := interval[1 : len(interval)-1]
-
-			// Range with two bounds: [lower,upper] or (lower,upper)
-			if strings.Contains(body, ",") {
-				parts := strings.SplitN(body, ",", 2)
-				left := strings.TrimSpace(parts[0])
-				right := strings.TrimSpace(parts[1])
-				var bounds []types.VersionConstraint
-
-				if left != "" {
-					op := types.OpGt
-					if leftBracket == '[' {
-						op = types.OpGte
-					}
-					bounds = append(bounds, types.VersionConstraint{
-						Value:    dependency.Parse(types.RawVersion(left), types.Semver),
-						Operator: op,
-					})
-				}
-				if right != "" {
-					op := types.OpLt
-					if rightBracket == ']' {
-						op = types.OpLte
-					}
-					bounds = append(bounds, types.VersionConstraint{
-						Value:    dependency.Parse(types.RawVersion(right), types.Semver),
-						Operator: op,
-					})
-				}
+var invalidMavenRange = types.VersionConstraintExpression{
+	{{Value: types.InvalidVersion, Operator: types.OpEq}},
+}
 
-				// Both bounds are AND constraints, wrap in single inner array
-				if len(bounds) > 0 {
-					return [][]types.VersionConstraint{bounds}
-				}
-				return nil
-			}
+// parseMavenRestriction parses a single range with its brackets, e.g., [1.0,2.0)
+// or [1.0]. A range without bounds, e.g., [,), accepts any version and has no
+// constraints.
+func parseMavenRestriction(
+	r string,
+	scheme types.VersionScheme,
+) (and []types.VersionConstraint, ok bool) {
+	lowerInclusive, upperInclusive := r[0] == '[', r[len(r)-1] == ']'
+	body := r[1 : len(r)-1]
+	parse := func(s string) types.ComparableVersion {
+		return dependency.Parse(types.RawVersion(s), scheme)
+	}
 
-			// Exact version match: [1.0]
-			body = strings.TrimSpace(body)
-			if body != "" && leftBracket == '[' && rightBracket == ']' {
-				return [][]types.VersionConstraint{
-					{
-						{
-							Value:    dependency.Parse(types.RawVersion(body), types.Semver),
-							Operator: types.OpEq,
-						},
-					},
-				}
-			}
+	lower, upper, isRange := strings.Cut(body, ",")
+	if !isRange {
+		// An exact version must be in brackets
+		if body == "" || !lowerInclusive || !upperInclusive {
+			return nil, false
 		}
+		return []types.VersionConstraint{{Value: parse(body), Operator: types.OpEq}}, true
+	}
+	if strings.Contains(upper, ",") {
+		return nil, false
 	}
+	if lower != "" {
+		and = append(
+			and, types.VersionConstraint{
+				Value:    parse(lower),
+				Operator: tools.Ternary(lowerInclusive, types.OpGte, types.OpGt),
+			},
+		)
+	}
+	if upper != "" {
+		and = append(
+			and, types.VersionConstraint{
+				Value:    parse(upper),
+				Operator: tools.Ternary(upperInclusive, types.OpLte, types.OpLt),
+			},
+		)
+	}
+	if lower != "" && upper != "" && and[1].Value.Lt(and[0].Value) {
+		return nil, false
+	}
+	return and, true
+}
 
-	// Single constraint: >=1.0, >1.0, <=2.0, <2.0, etc.
+// parseMavenOperator parses a version with an operator, e.g., >=1.0.
+func parseMavenOperator(
+	interval string,
+	scheme types.VersionScheme,
+) []types.VersionConstraint {
 	version := strings.TrimLeft(interval, "<>=!^~")
 	req := types.VersionConstraint{
-		Value: dependency.Parse(types.RawVersion(version), types.Semver),
+		Value: dependency.Parse(types.RawVersion(version), scheme),
 	}
 	if strings.HasPrefix(interval, "=") {
 		req.Operator = types.OpEq
@@ -179,6 +183,5 @@ func parseMavenVersionRange(interval string) [][]types.VersionConstraint {
 	} else {
 		req.Operator = types.OpEq
 	}
-
-	return [][]types.VersionConstraint{{req}}
+	return []types.VersionConstraint{req}
 }
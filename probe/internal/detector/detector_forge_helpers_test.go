@@ -0,0 +1,70 @@
+package detector
+
+import (
+	"testing"
+
+	"lucy/types"
+)
+
+func TestParseMavenVersionRange(t *testing.T) {
+	tests := []struct {
+		interval string
+		want     string // formatted by String
+	}{
+		// Ranges
+		{"[1.0,2.0)", ">=1.0.0 <2.0.0"},
+		{"(1.0,2.0]", ">1.0.0 <=2.0.0"},
+		{"[47,)", ">=47.0.0"},
+		{"(,1.0]", "<=1.0.0"},
+		{"[,)", "*"},
+		{"[ 1.0 , 2.0 )", ">=1.0.0 <2.0.0"},
+		// Exact versions
+		{"[1.0]", "=1.0.0"},
+		{"[47.2.20-beta]", "=47.2.20-beta"},
+		// Unions
+		{"[1.0,2.0),[3.0,)", ">=1.0.0 <2.0.0 || >=3.0.0"},
+		{"(,1.0],[1.2,)", "<=1.0.0 || >=1.2.0"},
+		{"[1.0],[1.2]", "=1.0.0 || =1.2.0"},
+		// Soft requirements and no constraint
+		{"1.20.1", "*"},
+		{"", "*"},
+		{"*", "*"},
+		// Not Maven, but used
+		{">=1.0", ">=1.0.0"},
+		{"<2", "<2.0.0"},
+		// Malformed
+		{"[1.0", "=invalid"},
+		{"[1.0,2.0", "=invalid"},
+		{"(1.0)", "=invalid"},
+		{"[]", "=invalid"},
+		{"[2.0,1.0]", "=invalid"},
+		{"[1.0,2.0,3.0]", "=invalid"},
+		{"[1.0,2.0),", "=invalid"},
+		{"[1.0,2.0)[3.0,)", "=invalid"},
+	}
+	for _, tt := range tests {
+		got := parseMavenVersionRange(tt.interval, types.Maven)
+		if got.String() != tt.want {
+			t.Errorf("parseMavenVersionRange(%q) = %s, want %s", tt.interval, got, tt.want)
+		}
+	}
+}
+
+func TestParseMavenVersionRangeContains(t *testing.T) {
+	exps := parseMavenVersionRange("[1.0,2.0),[3.0,)", types.Maven)
+	for _, v := range []struct {
+		v    types.ComparableVersion
+		want bool
+	}{
+		{types.ComparableVersion{Scheme: types.Maven, Major: 1}, true},
+		{types.ComparableVersion{Scheme: types.Maven, Major: 1, Minor: 9}, true},
+		{types.ComparableVersion{Scheme: types.Maven, Major: 2}, false},
+		{types.ComparableVersion{Scheme: types.Maven, Major: 2, Minor: 5}, false},
+		{types.ComparableVersion{Scheme: types.Maven, Major: 3}, true},
+		{types.ComparableVersion{Scheme: types.Maven, Major: 1, Prerelease: "-alpha"}, false},
+	} {
+		if got := exps.Contains(v.v); got != v.want {
+			t.Errorf("%s contains %s is %v, want %v", exps, v.v, got, v.want)
+		}
+	}
+}
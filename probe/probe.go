@@ -141,12 +141,38 @@ var modPaths = tools.Memoize(
 	},
 )
 
+// Packages analyzes a mod or plugin file that is not necessarily installed,
+// e.g., one about to be. A file may hold a package of each loader it supports.
+func Packages(filePath string) []types.Package {
+	return detector.Packages(filePath)
+}
+
+// IsPackageFile tells whether the file is named like a mod or plugin file, by
+// its extension in any case.
+func IsPackageFile(filePath string) bool {
+	return detector.PackageExt(filePath) != ""
+}
+
 var getEnvironment = tools.Memoize(
 	func() types.EnvironmentInfo {
 		return detector.Environment(".")
 	},
 )
 
+// McdrProxies returns the proxies set in the MCDR config, if any. Lucy uses
+// them when no proxy is configured for itself.
+func McdrProxies() (httpProxy string, httpsProxy string) {
+	env := getEnvironment()
+	if env.Mcdr == nil {
+		return "", ""
+	}
+	str := func(v any) string {
+		s, _ := v.(string)
+		return s
+	}
+	return str(env.Mcdr.HttpProxy), str(env.Mcdr.HttpsProxy)
+}
+
 var workPath = tools.Memoize(
 	func() string {
 		env := getEnvironment()
@@ -1,13 +1,14 @@
 package mcdr
 
 import (
+	"context"
 	"fmt"
 
 	"lucy/logger"
-	"lucy/probe"
 	"lucy/remote"
 	"lucy/syntax"
 	"lucy/types"
+	"lucy/util"
 )
 
 type self struct{}
@@ -18,13 +19,50 @@ func (s self) Name() types.Source {
 
 var Self self
 
-// Just a trivial type to implement the SearchResults interface
-type mcdrSearchResult []string
+// requester sends the requests of the source, see SetRequester.
+var requester = util.NewRequester(util.Client())
+
+// SetRequester hands the source the requester to send its requests to
+// the plugin catalogue through, e.g., one with the configured client.
+func SetRequester(r util.Requester) {
+	requester = r
+}
+
+// mcdrSearchResult implements the SearchResults interface. Hits without info
+// or meta are left with only their ids.
+type mcdrSearchResult []searchHit
+
+type searchHit struct {
+	id   string
+	info *pluginInfo
+	meta *pluginMeta
+}
 
 func (m mcdrSearchResult) ToSearchResults() types.SearchResults {
 	var res types.SearchResults
-	for _, id := range m {
-		res.Results = append(res.Results, syntax.ToProjectName(id))
+	for _, hit := range m {
+		r := types.SearchResult{
+			Name:   syntax.ToProjectName(hit.id),
+			Source: types.McdrCatalogue,
+			// MCDR plugins are server-only by nature
+			ClientSide: types.SideUnsupported,
+			ServerSide: types.SideRequired,
+		}
+		r.Title = r.Name.Title()
+		if hit.meta != nil {
+			r.Title = hit.meta.Name
+			r.Brief = hit.meta.Description.EnUs
+		}
+		if hit.info != nil {
+			r.Labels = hit.info.Labels
+			for _, author := range hit.info.Authors {
+				r.Authors = append(r.Authors, author.Name)
+			}
+			if r.Brief == "" {
+				r.Brief = firstLine(hit.info.Introduction.EnUs)
+			}
+		}
+		res.Results = append(res.Results, r)
 	}
 	res.Source = types.McdrCatalogue
 	return res
@@ -33,6 +71,7 @@ func (m mcdrSearchResult) ToSearchResults() types.SearchResults {
 // TODO: handle search options
 
 func (s self) Search(
+	ctx context.Context,
 	query string,
 	options types.SearchOptions,
 ) (res remote.RawSearchResults, err error) {
@@ -43,7 +82,7 @@ func (s self) Search(
 			options.Platform,
 		)
 	}
-	res, err = search(query)
+	res, err = search(ctx, query)
 	return
 }
 
@@ -87,12 +126,25 @@ func (s self) Information(name types.ProjectName) (
 	return info, nil
 }
 
+// Dependencies lists the plugins a release depends on, as declared in its
+// metadata. MCDR itself is listed there too, and is left out as it is the
+// platform rather than a package.
 func (s self) Dependencies(id types.PackageId) (
 	remote.RawPackageDependencies,
 	error,
 ) {
-	// TODO implement me
-	panic("implement me")
+	var err error
+	if id.Version.NeedsInfer() {
+		id, err = s.ParseAmbiguousVersion(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rel, err := getRelease(id.Name.Pep8String(), id.Version)
+	if err != nil {
+		return nil, err
+	}
+	return pluginDependencies(rel.Meta.Dependencies), nil
 }
 
 func (s self) Support(name types.ProjectName) (
@@ -103,21 +155,38 @@ func (s self) Support(name types.ProjectName) (
 	panic("implement me")
 }
 
+func (s self) Versions(name types.ProjectName) (
+	versions remote.RawProjectVersions,
+	err error,
+) {
+	history, err := getReleaseHistory(name.Pep8String())
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
 func (s self) ParseAmbiguousVersion(id types.PackageId) (
 	parsed types.PackageId,
 	err error,
 ) {
 	var rel *release
 	switch id.Version {
-	case types.LatestVersion, types.AllVersion:
+	// MCDR plugins are not bound to a game version, so the latest release is
+	// also the latest compatible one.
+	case types.LatestVersion, types.AllVersion, types.LatestCompatibleVersion:
 		rel, err = getLatestRelease(id.Name.Pep8String())
 		if err != nil {
 			return id, err
 		}
-	case types.LatestCompatibleVersion:
-		_ = probe.ServerInfo()
-		panic("implement me")
 	default:
+		if id.Version.IsRange() {
+			rel, err = getMatchingRelease(id)
+			if err != nil {
+				return id, err
+			}
+			break
+		}
 		return id, fmt.Errorf(
 			"cannot parse version %s for package %s",
 			id.Version,
@@ -1,10 +1,15 @@
 package mcdr
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
+	"lucy/dependency"
 	"lucy/github"
+	"lucy/logger"
+	"lucy/syntax"
 	"lucy/types"
 
 	"github.com/sahilm/fuzzy"
@@ -12,14 +17,15 @@ import (
 
 const (
 	pluginCatalogueRepoEndpoint = `https://api.github.com/repos/MCDReforged/PluginCatalogue/contents/`
+	pluginCatalogueRawEndpoint  = `https://raw.githubusercontent.com/MCDReforged/PluginCatalogue/`
 	branchMaster                = "?ref=master"
 	branchCatalogue             = "?ref=catalogue" // I haven't figured out the difference yet
 	branchMeta                  = "?ref=meta"
 )
 
-func search(query string) (mcdrSearchResult, error) {
+func search(ctx context.Context, query string) (mcdrSearchResult, error) {
 	ghEndpoint := pluginCatalogueRepoEndpoint + ("plugins/") + branchCatalogue
-	err, msg, items := github.GetDirectoryFromGitHub(ghEndpoint)
+	err, msg, items := github.GetDirectoryFromGitHub(ctx, requester, ghEndpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -33,17 +39,75 @@ func search(query string) (mcdrSearchResult, error) {
 	}
 
 	matches := fuzzy.Find(query, pluginIds)
-	result := make([]string, 0, len(matches))
+	result := make(mcdrSearchResult, 0, len(matches))
 	for _, match := range matches {
-		result = append(result, pluginIds[match.Index])
+		result = append(result, searchHit{id: pluginIds[match.Index]})
+	}
+	enrichSearchResult(ctx, result)
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 	return result, nil
 }
 
+// maxEnrichedHits limits how many search hits are enriched with metadata.
+// Each hit costs two requests, and broad queries can match most of the
+// catalogue.
+const maxEnrichedHits = 20
+
+// enrichSearchResult fetches the plugin info and meta for the top hits. They
+// are fetched through raw files so that searching does not exhaust the GitHub
+// API rate limit. Failures are not fatal, the hit is kept with its id only.
+func enrichSearchResult(ctx context.Context, hits mcdrSearchResult) {
+	var wg sync.WaitGroup
+	for i := range hits[:min(len(hits), maxEnrichedHits)] {
+		wg.Add(1)
+		go func(hit *searchHit) {
+			defer wg.Done()
+			info := &pluginInfo{}
+			err := getRawCatalogueFile(
+				ctx,
+				"master/plugins/"+hit.id+"/plugin_info.json",
+				hit.id,
+				info,
+			)
+			if err != nil {
+				logger.Debug(err)
+			} else {
+				hit.info = info
+			}
+			meta := &pluginMeta{}
+			err = getRawCatalogueFile(ctx, "meta/"+hit.id+"/meta.json", hit.id, meta)
+			if err != nil {
+				logger.Debug(err)
+			} else {
+				hit.meta = meta
+			}
+		}(&hits[i])
+	}
+	wg.Wait()
+}
+
+// getRawCatalogueFile decodes a json file in the catalogue repository into v.
+// path is relative to the repository root and starts with the branch name.
+func getRawCatalogueFile(ctx context.Context, path string, id string, v any) error {
+	err, msg, data := github.GetRawFileFromGitHub(ctx, requester, pluginCatalogueRawEndpoint+path)
+	if err != nil {
+		return err
+	}
+	if msg != nil && msg.Message != "" {
+		if msg.Status == "404" {
+			return ErrPluginNotFound(id)
+		}
+		return fmt.Errorf("%w: %s", ErrorGhApi, msg.Message)
+	}
+	return json.Unmarshal(data, v)
+}
+
 func getInfo(id string) (*pluginInfo, error) {
 	ghEndpoint := pluginCatalogueRepoEndpoint + ("plugins/") + id + "/plugin_info.json" + branchMaster
 	var data []byte
-	err, msg, data := github.GetFileFromGitHub(ghEndpoint)
+	err, msg, data := github.GetFileFromGitHub(context.Background(), requester, ghEndpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +134,7 @@ func getInfo(id string) (*pluginInfo, error) {
 
 func getMeta(id string) (*pluginMeta, error) {
 	ghEndpoint := pluginCatalogueRepoEndpoint + id + "/meta.json" + branchMeta
-	err, msg, data := github.GetFileFromGitHub(ghEndpoint)
+	err, msg, data := github.GetFileFromGitHub(context.Background(), requester, ghEndpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -104,12 +168,7 @@ func getRelease(id string, version types.RawVersion) (*release, error) {
 			return &rel, nil
 		}
 	}
-	return nil, fmt.Errorf(
-		"%w for plugin %s: %s",
-		ErrVersionNotFound,
-		id,
-		version,
-	)
+	return nil, ErrVersionNotFound(id, version.String())
 }
 
 func getLatestRelease(id string) (*release, error) {
@@ -120,9 +179,37 @@ func getLatestRelease(id string) (*release, error) {
 	return &history.Releases[history.LatestVersionIndex], nil
 }
 
+// getMatchingRelease picks the highest release matching the range in the id.
+// Prereleases are only picked if no release matches.
+func getMatchingRelease(id types.PackageId) (*release, error) {
+	exps, err := syntax.Constraint(id)
+	if err != nil {
+		return nil, err
+	}
+	history, err := getReleaseHistory(id.Name.Pep8String())
+	if err != nil {
+		return nil, err
+	}
+	for _, releaseOnly := range []bool{true, false} {
+		var candidates []*release
+		var versions []types.RawVersion
+		for i, rel := range history.Releases {
+			if releaseOnly && rel.Prerelease {
+				continue
+			}
+			candidates = append(candidates, &history.Releases[i])
+			versions = append(versions, types.RawVersion(rel.Meta.Version))
+		}
+		if i := dependency.Highest(versions, exps, types.Semver); i != -1 {
+			return candidates[i], nil
+		}
+	}
+	return nil, ErrVersionNotFound(id.Name.Pep8String(), id.Version.String())
+}
+
 func getReleaseHistory(id string) (*pluginRelease, error) {
 	ghEndpoint := pluginCatalogueRepoEndpoint + id + "/release.json" + branchMeta
-	err, msg, data := github.GetFileFromGitHub(ghEndpoint)
+	err, msg, data := github.GetFileFromGitHub(context.Background(), requester, ghEndpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +230,7 @@ func getReleaseHistory(id string) (*pluginRelease, error) {
 
 func getRepository(id string) (*pluginRepo, error) {
 	ghEndpoint := pluginCatalogueRepoEndpoint + id + "/repository.json" + branchMeta
-	err, msg, data := github.GetFileFromGitHub(ghEndpoint)
+	err, msg, data := github.GetFileFromGitHub(context.Background(), requester, ghEndpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -52,3 +52,15 @@ func parseRequiredVersion(s string) (reqs []types.VersionConstraint) {
 	}
 	return
 }
+
+// firstLine returns the first non-empty line of a markdown text, with leading
+// heading marks removed.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "# ")
+		if line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -1,12 +1,18 @@
 package mcdr
 
 import (
+	"maps"
+	"slices"
 	"time"
 
+	"lucy/syntax"
 	"lucy/tools"
 	"lucy/types"
 )
 
+// mcdrPluginId is how plugins refer to MCDR itself in their dependencies.
+const mcdrPluginId = "mcdreforged"
+
 // GitHub API file ref: https://api.github.com/repos/MCDReforged/PluginCatalogue/contents/plugins/{plugin_name}/plugin_info.json
 // The purpose of this file is quite unclear to me.
 // For this project, meta.json under the meta branch is more handy.
@@ -36,6 +42,22 @@ type pluginRelease struct {
 	LatestVersionIndex int       `json:"latest_version_index"`
 	Releases           []release `json:"releases"`
 }
+
+func (p *pluginRelease) ToProjectVersions() []types.ProjectVersion {
+	res := make([]types.ProjectVersion, 0, len(p.Releases))
+	for _, r := range p.Releases {
+		res = append(
+			res, types.ProjectVersion{
+				Version:    types.RawVersion(r.Meta.Version),
+				Platforms:  []types.Platform{types.Mcdr},
+				Prerelease: r.Prerelease,
+				Published:  r.CreatedAt,
+			},
+		)
+	}
+	return res
+}
+
 type release struct {
 	Url         string     `json:"url"`
 	Name        string     `json:"name"`
@@ -49,9 +71,11 @@ type release struct {
 
 func (r release) ToPackageRemote() types.PackageRemote {
 	remote := types.PackageRemote{
-		Source:   types.McdrCatalogue,
-		FileUrl:  r.Asset.BrowserDownloadUrl,
-		Filename: r.Asset.Name,
+		Source:     types.McdrCatalogue,
+		FileUrl:    r.Asset.BrowserDownloadUrl,
+		Filename:   r.Asset.Name,
+		Hash:       r.Asset.HashSha256,
+		HashMethod: types.HashSha256,
 	}
 	return remote
 }
@@ -67,6 +91,27 @@ type asset struct {
 	HashSha256         string    `json:"hash_sha256"`
 }
 
+// pluginDependencies maps plugin ids to version requirements, e.g., ">=1.2.0".
+type pluginDependencies map[string]string
+
+func (d pluginDependencies) ToPackageDependencies() types.PackageDependencies {
+	res := types.PackageDependencies{Authentic: true}
+	for _, id := range slices.Sorted(maps.Keys(d)) {
+		if id == mcdrPluginId {
+			continue
+		}
+		dep := types.Dependency{
+			Id:        types.PackageId{Platform: types.Mcdr, Name: syntax.ToProjectName(id)},
+			Mandatory: true,
+		}
+		if reqs := parseRequiredVersion(d[id]); len(reqs) != 0 {
+			dep.Constraint = types.VersionConstraintExpression{reqs}
+		}
+		res.Value = append(res.Value, dep)
+	}
+	return res
+}
+
 // GitHub API file ref: https://api.github.com/repos/MCDReforged/PluginCatalogue/contents/{plugin_name}/meta.json?ref=meta
 type pluginMeta struct {
 	SchemaVersion int               `json:"schema_version"`
@@ -12,17 +12,19 @@
 package modrinth
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
-	"net/http"
 
+	"lucy/dependency"
 	"lucy/tools"
 
 	"lucy/remote"
 	"lucy/types"
 
 	"lucy/logger"
+	"lucy/util"
 )
 
 type self struct{}
@@ -33,11 +35,21 @@ func (s self) Name() types.Source {
 
 var Self self
 
+// requester sends the requests of the source, see SetRequester.
+var requester = util.NewRequester(util.Client())
+
+// SetRequester hands the source the requester to send its requests to
+// Modrinth through, e.g., one with the configured client.
+func SetRequester(r util.Requester) {
+	requester = r
+}
+
 // Search
 //
 // For Modrinth search API, see:
 // https://docs.modrinth.com/api/operations/searchprojects/
 func (s self) Search(
+	ctx context.Context,
 	query string,
 	options types.SearchOptions,
 ) (res remote.RawSearchResults, err error) {
@@ -67,11 +79,11 @@ func (s self) Search(
 
 	// Make the call to Modrinth API
 	logger.Debug("searching via modrinth api: " + searchUrl)
-	httpRes, err := http.Get(searchUrl)
-	defer tools.CloseReader(httpRes.Body, logger.Warn)
+	httpRes, err := requester.Get(ctx, searchUrl)
 	if err != nil {
-		return nil, ErrInvalidAPIResponse
+		return nil, err
 	}
+	defer tools.CloseReader(httpRes.Body, logger.Warn)
 	data, err := io.ReadAll(httpRes.Body)
 	if err != nil {
 		return nil, err
@@ -89,6 +101,9 @@ func (s self) Fetch(id types.PackageId) (
 	err error,
 ) {
 	id, err = s.ParseAmbiguousVersion(id)
+	if err != nil {
+		return nil, err
+	}
 	version, err := getVersion(id)
 	if err != nil {
 		return nil, err
@@ -120,14 +135,55 @@ func (s self) Support(name types.ProjectName) (
 	return project, nil
 }
 
+func (s self) Versions(name types.ProjectName) (
+	versions remote.RawProjectVersions,
+	err error,
+) {
+	list, err := listVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	return versionsResponse(list), nil
+}
+
 var ErrInvalidAPIResponse = errors.New("invalid data from modrinth api")
 
+// Dependencies lists the required and optional dependencies of a version.
+// Embedded dependencies are shipped inside the file, and incompatible ones are
+// not dependencies at all, so both are left out.
 func (s self) Dependencies(id types.PackageId) (
 	deps remote.RawPackageDependencies,
 	err error,
 ) {
-	// TODO implement me
-	panic("implement me")
+	id, err = s.ParseAmbiguousVersion(id)
+	if err != nil {
+		return nil, err
+	}
+	version, err := getVersion(id)
+	if err != nil {
+		return nil, err
+	}
+	res := dependenciesResult{}
+	for i, d := range version.Dependencies {
+		if d.DependencyType != required && d.DependencyType != optional {
+			continue
+		}
+		p, err := DependencyToPackage(id, &version.Dependencies[i])
+		if err != nil {
+			return nil, err
+		}
+		dep := types.Dependency{
+			Id:        types.PackageId{Platform: p.Platform, Name: p.Name},
+			Mandatory: d.DependencyType == required,
+		}
+		if d.VersionId != "" {
+			dep.Constraint = types.VersionConstraintExpression{
+				{{Value: dependency.Parse(p.Version, types.Semver), Operator: types.OpEq}},
+			}
+		}
+		res = append(res, dep)
+	}
+	return res, nil
 }
 
 func (s self) ParseAmbiguousVersion(p types.PackageId) (
@@ -144,7 +200,10 @@ func (s self) ParseAmbiguousVersion(p types.PackageId) (
 	case types.AllVersion, types.NoVersion, types.LatestVersion:
 		v, err = latestVersion(p.Name)
 	default:
-		return p, nil
+		if !p.Version.IsRange() {
+			return p, nil
+		}
+		v, err = matchingVersion(p)
 	}
 	if err != nil {
 		return p, err
@@ -0,0 +1,12 @@
+package modrinth
+
+import "lucy/util"
+
+const apiHost = "api.modrinth.com"
+
+// SetToken authenticates requests to the Modrinth API with a personal access
+// token, which makes the unlisted and draft projects and versions the token
+// can see available.
+func SetToken(token string) {
+	util.SetAuthorization(apiHost, token)
+}
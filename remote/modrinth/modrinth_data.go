@@ -185,17 +185,39 @@ type searchResultResponse struct {
 func (s *searchResultResponse) ToSearchResults() types.SearchResults {
 	res := types.SearchResults{
 		Source:  types.Modrinth,
-		Results: make([]types.ProjectName, 0, s.TotalHits),
+		Results: make([]types.SearchResult, 0, len(s.Hits)),
 	}
 
 	// The hits should already be sorted by whatever index passed in.
 	for _, hit := range s.Hits {
-		res.Results = append(res.Results, syntax.ToProjectName(hit.Slug))
+		res.Results = append(
+			res.Results, types.SearchResult{
+				Name:         syntax.ToProjectName(hit.Slug),
+				Source:       types.Modrinth,
+				Title:        hit.Title,
+				Brief:        hit.Description,
+				Authors:      []string{hit.Author},
+				Labels:       hit.DisplayCategories,
+				Downloads:    hit.Downloads,
+				GameVersions: toRawVersions(hit.Versions),
+				ClientSide:   types.SideSupport(hit.ClientSide),
+				ServerSide:   types.SideSupport(hit.ServerSide),
+				Updated:      hit.DateModified,
+			},
+		)
 	}
 
 	return res
 }
 
+func toRawVersions(versions []string) []types.RawVersion {
+	res := make([]types.RawVersion, 0, len(versions))
+	for _, v := range versions {
+		res = append(res, types.RawVersion(v))
+	}
+	return res
+}
+
 // versionResponse
 //
 // Docs
@@ -225,13 +247,48 @@ type versionResponse struct {
 
 func (v versionResponse) ToPackageRemote() types.PackageRemote {
 	remote := types.PackageRemote{
-		Source:   types.Modrinth,
-		FileUrl:  v.Files[0].Url,
-		Filename: v.Files[0].Filename,
+		Source:     types.Modrinth,
+		FileUrl:    v.Files[0].Url,
+		Filename:   v.Files[0].Filename,
+		Hash:       v.Files[0].Hashes.Sha512,
+		HashMethod: types.HashSha512,
 	}
 	return remote
 }
 
+// versionsResponse is the list of versions of a project.
+//
+// Docs
+// https://docs.modrinth.com/api/operations/getprojectversions/
+type versionsResponse []*versionResponse
+
+func (vs versionsResponse) ToProjectVersions() []types.ProjectVersion {
+	res := make([]types.ProjectVersion, 0, len(vs))
+	for _, v := range vs {
+		pv := types.ProjectVersion{
+			Version:      types.RawVersion(v.VersionNumber),
+			GameVersions: toRawVersions(v.GameVersions),
+			Prerelease:   v.VersionType != "release",
+			Published:    v.DatePublished,
+		}
+		for _, loader := range v.Loaders {
+			pv.Platforms = append(pv.Platforms, types.Platform(loader))
+		}
+		res = append(res, pv)
+	}
+	return res
+}
+
+// dependenciesResult is the dependencies of a version, with the project ids
+// given by Modrinth resolved to slugs.
+type dependenciesResult []types.Dependency
+
+// ToPackageDependencies marks the dependencies as not authentic, since they
+// are entered on Modrinth by hand rather than read from the file.
+func (d dependenciesResult) ToPackageDependencies() types.PackageDependencies {
+	return types.PackageDependencies{Value: d, Authentic: false}
+}
+
 type dependencyType string
 
 const (
@@ -1,10 +1,9 @@
 package modrinth
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"io"
-	"net/http"
 
 	"lucy/syntax"
 
@@ -12,9 +11,11 @@ import (
 )
 
 func getProjectId(slug types.ProjectName) (id string, err error) {
-	res, _ := http.Get(projectUrl(string(slug)))
+	data, _, err := requester.GetCached(context.Background(), projectUrl(string(slug)))
+	if err != nil {
+		return "", err
+	}
 	modrinthProject := projectResponse{}
-	data, _ := io.ReadAll(res.Body)
 	err = json.Unmarshal(data, &modrinthProject)
 	if err != nil {
 		return "", ENoProject
@@ -24,8 +25,10 @@ func getProjectId(slug types.ProjectName) (id string, err error) {
 }
 
 func getProjectById(id string) (project *projectResponse, err error) {
-	res, _ := http.Get(projectUrl(id))
-	data, _ := io.ReadAll(res.Body)
+	data, _, err := requester.GetCached(context.Background(), projectUrl(id))
+	if err != nil {
+		return nil, err
+	}
 	project = &projectResponse{}
 	err = json.Unmarshal(data, project)
 	if err != nil {
@@ -38,8 +41,10 @@ func getProjectByName(slug types.ProjectName) (
 	project *projectResponse,
 	err error,
 ) {
-	res, _ := http.Get(projectUrl(string(slug)))
-	data, _ := io.ReadAll(res.Body)
+	data, _, err := requester.GetCached(context.Background(), projectUrl(string(slug)))
+	if err != nil {
+		return nil, err
+	}
 	project = &projectResponse{}
 	err = json.Unmarshal(data, project)
 	if err != nil {
@@ -52,8 +57,10 @@ func getProjectMembers(id string) (
 	members []*memberResponse,
 	err error,
 ) {
-	res, _ := http.Get(projectMemberUrl(id))
-	data, _ := io.ReadAll(res.Body)
+	data, _, err := requester.GetCached(context.Background(), projectMemberUrl(id))
+	if err != nil {
+		return nil, err
+	}
 	err = json.Unmarshal(data, &members)
 	if err != nil {
 		return nil, ENoMember
@@ -78,18 +85,27 @@ func DependencyToPackage(
 	// package is the same as the platform of the dependency.
 	p.Platform = dependent.Platform
 
-	if dependency.VersionId != "" && dependency.ProjectId != "" {
-		version, _ = getVersionById(dependency.VersionId)
-		project, _ = getProjectById(dependency.ProjectId)
-	} else if dependency.VersionId != "" {
-		version, _ = getVersionById(dependency.VersionId)
-		project, _ = getProjectById(version.ProjectId)
-	} else if dependency.ProjectId != "" {
-		project, _ = getProjectById(dependency.ProjectId)
+	switch {
+	case dependency.VersionId != "":
+		if version, err = getVersionById(dependency.VersionId); err != nil {
+			return p, err
+		}
+		projectId := dependency.ProjectId
+		if projectId == "" {
+			projectId = version.ProjectId
+		}
+		if project, err = getProjectById(projectId); err != nil {
+			return p, err
+		}
+	case dependency.ProjectId != "":
+		if project, err = getProjectById(dependency.ProjectId); err != nil {
+			return p, err
+		}
 		// This is not safe, TODO: use better inference method
-		version, _ = latestVersion(syntax.ToProjectName(project.Slug))
-		p.Version = types.LatestVersion
-	} else {
+		if version, err = latestVersion(syntax.ToProjectName(project.Slug)); err != nil {
+			return p, err
+		}
+	default:
 		return p, ErrorInvalidDependency
 	}
 
@@ -1,12 +1,16 @@
 package modrinth
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"io"
-	"net/http"
+	"fmt"
+	"regexp"
+	"slices"
 
+	"lucy/dependency"
 	"lucy/logger"
+	"lucy/syntax"
 
 	"lucy/probe"
 	"lucy/types"
@@ -27,11 +31,7 @@ func listVersions(slug types.ProjectName) (
 	versions []*versionResponse,
 	err error,
 ) {
-	res, err := http.Get(versionsUrl(slug))
-	if err != nil {
-		return nil, err
-	}
-	data, err := io.ReadAll(res.Body)
+	data, _, err := requester.GetCached(context.Background(), versionsUrl(slug))
 	if err != nil {
 		return nil, err
 	}
@@ -65,12 +65,96 @@ func getVersion(id types.PackageId) (
 			return version, nil
 		}
 	}
+	// Not a version of the project, it may be the version of the game to
+	// pick the project for, e.g., create@1.20.1
+	for _, version := range versions {
+		if slices.Contains(version.GameVersions, id.Version.String()) &&
+			versionSupportsLoader(version, id.Platform) &&
+			(v == nil || isNewerRelease(version, v)) {
+			v = version
+		}
+	}
+	if v != nil {
+		logger.Info(
+			"no version " + id.Version.String() + " of " + id.Name.Title() +
+				", taking it as the version of the game: " + v.VersionNumber,
+		)
+		return v, nil
+	}
 	return nil, ENoVersion
 }
 
+// isNewerRelease prefers releases over betas and alphas, then the later one.
+func isNewerRelease(version, than *versionResponse) bool {
+	if (version.VersionType == "release") != (than.VersionType == "release") {
+		return version.VersionType == "release"
+	}
+	return version.DatePublished.After(than.DatePublished)
+}
+
+// matchingVersion picks the highest version matching the range in the id.
+// Only versions for the version of the game and the loader of the server are
+// candidates, as builds of one version number for different versions of the
+// game are otherwise tied, see versionNumber. Releases are preferred, betas and
+// alphas are only picked if no release matches.
+func matchingVersion(id types.PackageId) (
+	v *versionResponse,
+	err error,
+) {
+	exps, err := syntax.Constraint(id)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := listVersions(id.Name)
+	if err != nil {
+		return nil, err
+	}
+	serverInfo := probe.ServerInfo()
+	known := serverInfo.Executable != probe.UnknownExecutable
+	if !known {
+		logger.Info("no executable found, picking from the versions for any version of the game")
+	}
+	for _, releaseOnly := range []bool{true, false} {
+		var candidates []*versionResponse
+		var numbers []types.RawVersion
+		for _, version := range versions {
+			if (releaseOnly && version.VersionType != "release") ||
+				!versionSupportsLoader(version, id.Platform) ||
+				(known && !versionSupportsServer(version, serverInfo)) {
+				continue
+			}
+			candidates = append(candidates, version)
+			numbers = append(numbers, versionNumber(version))
+		}
+		if i := dependency.Highest(numbers, exps, dependency.SchemeOf(id)); i != -1 {
+			logger.Debug("version " + id.Version.String() + " of " + id.Name.String() + " resolved to " + candidates[i].VersionNumber)
+			return candidates[i], nil
+		}
+	}
+	if known {
+		return nil, fmt.Errorf(
+			"%w: none for %s matches %s",
+			ENoVersion, serverInfo.Executable.GameVersion, id.Version,
+		)
+	}
+	return nil, fmt.Errorf("%w: none matches %s", ENoVersion, id.Version)
+}
+
+// gamePrefix is the version of the game some projects prefix their version
+// numbers with, e.g., mc1.20.1-0.5.8 of Sodium
+var gamePrefix = regexp.MustCompile(`^mc\d+(?:\.\d+)*-`)
+
+// versionNumber returns the version number of the project, without the game
+// version prefixed to it.
+func versionNumber(version *versionResponse) types.RawVersion {
+	return types.RawVersion(gamePrefix.ReplaceAllString(version.VersionNumber, ""))
+}
+
 func getVersionById(id string) (v *versionResponse, err error) {
-	res, _ := http.Get(versionUrl(id))
-	data, _ := io.ReadAll(res.Body)
+	data, _, err := requester.GetCached(context.Background(), versionUrl(id))
+	if err != nil {
+		return nil, err
+	}
 	v = &versionResponse{}
 	err = json.Unmarshal(data, v)
 	if err != nil {
@@ -91,6 +175,13 @@ func versionSupportsLoader(
 	return false
 }
 
+// versionSupportsServer tells whether the version is for the version of the
+// game and the loader the server runs.
+func versionSupportsServer(version *versionResponse, serverInfo types.ServerInfo) bool {
+	return slices.Contains(version.GameVersions, serverInfo.Executable.GameVersion.String()) &&
+		versionSupportsLoader(version, serverInfo.Executable.ModLoader)
+}
+
 func latestVersion(slug types.ProjectName) (
 	v *versionResponse,
 	err error,
@@ -132,6 +223,9 @@ func LatestCompatibleVersion(slug types.ProjectName) (
 		return v, nil
 	}
 	for _, version := range versions {
+		if !versionSupportsLoader(version, serverInfo.Executable.ModLoader) {
+			continue
+		}
 		for _, gameVersion := range version.GameVersions {
 			if gameVersion == serverInfo.Executable.GameVersion.String() &&
 				version.VersionType == "release" &&
@@ -140,5 +234,8 @@ func LatestCompatibleVersion(slug types.ProjectName) (
 			}
 		}
 	}
+	if v == nil {
+		return nil, ENoVersion
+	}
 	return v, nil
 }
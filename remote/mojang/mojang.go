@@ -2,10 +2,10 @@ package mojang
 
 import (
 	"encoding/json"
-	"io"
-	"net/http"
+	"slices"
 
 	"lucy/exttype"
+	"lucy/util"
 )
 
 const VersionManifestURL = "https://piston-meta.mojang.com/mc/game/version_manifest_v2.json"
@@ -13,21 +13,29 @@ const VersionManifestURL = "https://piston-meta.mojang.com/mc/game/version_manif
 func getVersionManifest() (manifest *exttype.ApiMojangMinecraftVersionManifest, err error) {
 	manifest = &exttype.ApiMojangMinecraftVersionManifest{}
 
-	// TODO: Add cache mechanism if http call is too slow or fails
-	resp, err := http.Get(VersionManifestURL)
+	data, _, err := util.GetCached(VersionManifestURL)
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	err = json.Unmarshal(data, manifest)
 	if err != nil {
 		return nil, err
 	}
 
-	err = json.Unmarshal(data, manifest)
+	return manifest, nil
+}
+
+// ReleaseOrder returns the ids of all versions of the game, oldest first. The
+// manifest lists them newest first.
+func ReleaseOrder() ([]string, error) {
+	manifest, err := getVersionManifest()
 	if err != nil {
 		return nil, err
 	}
-
-	return manifest, nil
+	ids := make([]string, 0, len(manifest.Versions))
+	for _, v := range slices.Backward(manifest.Versions) {
+		ids = append(ids, v.Id)
+	}
+	return ids, nil
 }
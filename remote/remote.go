@@ -7,7 +7,8 @@
 package remote
 
 import (
-	"fmt"
+	"context"
+	"slices"
 
 	"lucy/types"
 )
@@ -30,8 +31,12 @@ func Dependencies(
 	source SourceHandler,
 	id types.PackageId,
 ) (deps *types.PackageDependencies, err error) {
-	// TODO: Implement
-	return nil, fmt.Errorf("%w: %s", ErrorSourceNotSupported, source)
+	raw, err := source.Dependencies(id)
+	if err != nil {
+		return nil, err
+	}
+	res := raw.ToPackageDependencies()
+	return &res, nil
 }
 
 func PlatformSupport(source types.Source, name types.ProjectName) (
@@ -54,12 +59,30 @@ func Information(
 	return info, nil
 }
 
+// Versions lists the published versions of a project, newest first.
+func Versions(
+	source SourceHandler,
+	name types.ProjectName,
+) (versions []types.ProjectVersion, err error) {
+	raw, err := source.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	versions = raw.ToProjectVersions()
+	slices.SortStableFunc(
+		versions,
+		func(a, b types.ProjectVersion) int { return b.Published.Compare(a.Published) },
+	)
+	return versions, nil
+}
+
 func Search(
+	ctx context.Context,
 	source SourceHandler,
 	query types.ProjectName,
 	option types.SearchOptions,
 ) (res types.SearchResults, err error) {
-	raw, err := source.Search(string(query), option)
+	raw, err := source.Search(ctx, string(query), option)
 	if err != nil {
 		return res, err
 	}
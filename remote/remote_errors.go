@@ -13,6 +13,7 @@ var (
 	ErrorNoPackage           = errors.New("no such package")
 	ErrorNoResults           = errors.New("no results found")
 	ErrorNoVersion           = errors.New("no version found")
+	ErrorSearchTimeout       = errors.New("search timed out")
 	ErrorUnsupportedPlatform = errors.New("unsupported platform")
 )
 
@@ -1,9 +1,14 @@
 package remote
 
-import "lucy/types"
+import (
+	"context"
+
+	"lucy/types"
+)
 
 type SourceHandler interface {
-	Search(query string, options types.SearchOptions) (
+	// Search stops and returns the error of ctx when it is cancelled.
+	Search(ctx context.Context, query string, options types.SearchOptions) (
 		res RawSearchResults,
 		err error,
 	)
@@ -23,6 +28,10 @@ type SourceHandler interface {
 		supports RawProjectSupport,
 		err error,
 	)
+	Versions(name types.ProjectName) (
+		versions RawProjectVersions,
+		err error,
+	)
 	ParseAmbiguousVersion(id types.PackageId) (
 		parsed types.PackageId,
 		err error,
@@ -47,6 +56,9 @@ type (
 	RawPackageDependencies interface {
 		ToPackageDependencies() types.PackageDependencies
 	}
+	RawProjectVersions interface {
+		ToProjectVersions() []types.ProjectVersion
+	}
 
 	// TODO: Consider make SortBy a method on the RawSearchResults interface
 
@@ -0,0 +1,186 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"lucy/tools"
+	"lucy/types"
+)
+
+// SearchMultiple queries all sources concurrently and merges their results
+// into a single ranked list.
+//
+// Every source shares the same timeout, after which the searches still running
+// are cancelled. A source that fails or does not respond in time is skipped,
+// and its error is returned in errs so that the caller can decide whether to
+// warn the user. ErrorNoResults is only returned when no source produced any
+// result at all.
+func SearchMultiple(
+	ctx context.Context,
+	sources []SourceHandler,
+	query types.ProjectName,
+	option types.SearchOptions,
+	timeout time.Duration,
+) (res types.SearchResults, errs []error, err error) {
+	type outcome struct {
+		source SourceHandler
+		res    types.SearchResults
+		err    error
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Buffered, so that cancelled sources can still deliver and exit after we
+	// stopped listening.
+	ch := make(chan outcome, len(sources))
+	for _, source := range sources {
+		go func(source SourceHandler) {
+			res, err := Search(ctx, source, query, option)
+			ch <- outcome{source: source, res: res, err: err}
+		}(source)
+	}
+
+	// Results are kept in the order of sources rather than the order of
+	// arrival, so that the output is deterministic.
+	collected := make(map[SourceHandler]types.SearchResults, len(sources))
+	responded := make(map[SourceHandler]bool, len(sources))
+	timedOut := func(source SourceHandler) error {
+		return fmt.Errorf("%w on %s", ErrorSearchTimeout, source.Name().Title())
+	}
+
+Collect:
+	for range sources {
+		select {
+		case o := <-ch:
+			responded[o.source] = true
+			if errors.Is(o.err, context.DeadlineExceeded) {
+				errs = append(errs, timedOut(o.source))
+				continue
+			}
+			if o.err != nil {
+				if !errors.Is(o.err, ErrorNoResults) {
+					errs = append(
+						errs,
+						fmt.Errorf("search on %s failed: %w", o.source.Name().Title(), o.err),
+					)
+				}
+				continue
+			}
+			collected[o.source] = o.res
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return res, errs, ctx.Err()
+			}
+			for _, source := range sources {
+				if !responded[source] {
+					errs = append(errs, timedOut(source))
+				}
+			}
+			break Collect
+		}
+	}
+
+	// A project is only a duplicate within its source, projects of the same
+	// name on different sources are different projects.
+	type key struct {
+		source types.Source
+		name   types.ProjectName
+	}
+	res.Source = types.AutoSource
+	seen := make(map[key]bool)
+	for _, source := range sources {
+		for _, hit := range collected[source].Results {
+			k := key{source: hit.Source, name: hit.Name}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			res.Results = append(res.Results, hit)
+		}
+	}
+	if len(res.Results) == 0 {
+		return res, errs, ErrorNoResults
+	}
+
+	rankSearchResults(query, option.IndexBy, res.Results)
+	return res, errs, nil
+}
+
+// Weights for the relevance score of a search hit. Name similarity dominates
+// so that an exact match always comes first, while downloads break the tie
+// between similarly named projects.
+const (
+	weightSimilarity = 0.75
+	weightPopularity = 0.25
+)
+
+// rankSearchResults sorts results in-place. Only relevance and downloads can
+// be re-ranked across sources; for other indexes the per-source order is kept.
+func rankSearchResults(
+	query types.ProjectName,
+	index types.SearchIndex,
+	results []types.SearchResult,
+) {
+	switch index {
+	case types.ByDownloads:
+		slices.SortStableFunc(
+			results,
+			func(a, b types.SearchResult) int { return b.Downloads - a.Downloads },
+		)
+	case types.ByRelevance, "":
+		maxDownloads := 0
+		for _, r := range results {
+			maxDownloads = max(maxDownloads, r.Downloads)
+		}
+		scored := make([]tools.KeyValue[types.SearchResult, float64], 0, len(results))
+		for _, r := range results {
+			scored = append(
+				scored, tools.KeyValue[types.SearchResult, float64]{
+					Item:  r,
+					Index: relevance(query, r, maxDownloads),
+				},
+			)
+		}
+		sorted := tools.SortAndExtract(
+			scored,
+			func(a, b tools.KeyValue[types.SearchResult, float64]) int {
+				switch {
+				case a.Index > b.Index:
+					return -1
+				case a.Index < b.Index:
+					return 1
+				default:
+					return 0
+				}
+			},
+		)
+		copy(results, sorted)
+	}
+}
+
+func relevance(
+	query types.ProjectName,
+	r types.SearchResult,
+	maxDownloads int,
+) float64 {
+	q := strings.ToLower(query.String())
+	n := strings.ToLower(r.Name.String())
+	similarity := (tools.JaroWinklerSimilarity(q, n) +
+		(1 - tools.NormalizedLevenshteinDistance(q, n))) / 2
+
+	// Downloads span several orders of magnitude, use a log scale so that
+	// popular projects do not drown everything else.
+	popularity := 0.0
+	if maxDownloads > 0 {
+		popularity = math.Log1p(float64(r.Downloads)) /
+			math.Log1p(float64(maxDownloads))
+	}
+	return weightSimilarity*similarity + weightPopularity*popularity
+}
@@ -12,13 +12,26 @@
 //   - minecraft@1.19 (recommended)
 //   - minecraft/minecraft@1.16.5 (= minecraft@1.16.5)
 //   - 1.8.9 (= minecraft@1.8.9)
+//
+// The version can also be a range in the npm syntax, see dependency.ParseRange,
+// and the best version matching it is picked. Quote ranges with spaces in the
+// shell.
+//   - sodium@^0.5
+//   - carpet@">=1.4.100 <1.5"
+//   - fabric-api@~0.92
+//
+// For mods, a version that is not one of the mod is taken as the version of
+// the game to pick the mod for, e.g., create@1.20.1.
 package syntax
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
+	"lucy/dependency"
 	"lucy/logger"
+	"lucy/tools"
 	"lucy/types"
 )
 
@@ -54,23 +67,73 @@ func sanitize(s string) string {
 var (
 	ESyntax   = errors.New("invalid syntax")
 	EPlatform = errors.New("invalid platform")
+	EVersion  = errors.New("invalid version")
 )
 
-// Parse is exported to parse a string into a PackageId struct.
-func Parse(s string) (id types.PackageId) {
+// Error is an input that cannot be parsed as a package id. It unwraps to
+// ESyntax, EPlatform or EVersion. When printed, it points at the offending
+// character, and suggests a fix if there is one.
+type Error struct {
+	Input  string
+	Offset int // in bytes
+	Reason string
+	// Suggestion is the input fixed, e.g., neoforge/create for neofrge/create
+	Suggestion string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	s := fmt.Sprintf(
+		"%s: %s\n  %s\n  %s^",
+		e.Err,
+		e.Reason,
+		e.Input,
+		strings.Repeat(" ", len([]rune(e.Input[:e.Offset]))),
+	)
+	if e.Suggestion != "" {
+		s += "\n  did you mean `" + e.Suggestion + "`?"
+	}
+	return s
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Parse parses a string into a PackageId. An error is always an *Error.
+func Parse(s string) (id types.PackageId, err error) {
 	s = sanitize(s)
-	id = types.PackageId{}
-	var err error
 	id.Platform, id.Name, id.Version, err = parseOperatorAt(s)
 	if err != nil {
-		if errors.Is(err, ESyntax) {
-			panic(err)
-		} else {
-			logger.Fatal(err)
+		return types.PackageId{}, err
+	}
+	_, err = Constraint(id)
+	var rangeErr *dependency.RangeError
+	if errors.As(err, &rangeErr) {
+		return types.PackageId{}, &Error{
+			Input:  s,
+			Offset: strings.Index(s, "@") + 1 + rangeErr.Offset,
+			Reason: rangeErr.Message,
+			Err:    EVersion,
 		}
 	}
 	logger.Debug("parsed input as package: " + id.StringFull())
-	return
+	return id, nil
+}
+
+// Constraint parses the version of the package id into a constraint. A
+// single version is taken as exactly itself, whatever its shape, and any of
+// the special constants as any version.
+func Constraint(id types.PackageId) (types.VersionConstraintExpression, error) {
+	switch {
+	case id.Version.IsRange():
+		return dependency.ParseRange(string(id.Version), dependency.SchemeOf(id))
+	case id.Version.NeedsInfer():
+		return nil, nil
+	}
+	return types.VersionConstraintExpression{
+		{{Value: dependency.Parse(id.Version, dependency.SchemeOf(id)), Operator: types.OpEq}},
+	}, nil
 }
 
 // parseOperatorAt is called first since '@' operator always occur after '/' (equivalent
@@ -81,37 +144,72 @@ func parseOperatorAt(s string) (
 	v types.RawVersion,
 	err error,
 ) {
-	split := strings.Split(s, "@")
+	if strings.TrimSpace(s) == "" {
+		return "", "", "", &Error{Input: s, Reason: "empty package id", Err: ESyntax}
+	}
+	before, after, hasVersion := strings.Cut(s, "@")
+	if i := strings.Index(after, "@"); i != -1 {
+		return "", "", "", &Error{
+			Input:  s,
+			Offset: len(before) + 1 + i,
+			Reason: "more than one '@'",
+			Err:    ESyntax,
+		}
+	}
 
-	pl, n, err = parseOperatorSlash(split[0])
+	pl, n, err = parseOperatorSlash(s, before)
 	if err != nil {
-		return "", "", "", ESyntax
+		return "", "", "", err
 	}
 
-	if len(split) == 1 {
-		v = types.AllVersion
-	} else if len(split) == 2 {
-		v = types.RawVersion(split[1])
-		if v == types.NoVersion || v == types.AllVersion {
-			return "", "", "", ESyntax
+	if !hasVersion {
+		return pl, n, types.AllVersion, nil
+	}
+	v = types.RawVersion(after)
+	switch v {
+	case "":
+		return "", "", "", &Error{
+			Input:      s,
+			Offset:     len(s),
+			Reason:     "missing version after '@'",
+			Suggestion: before,
+			Err:        ESyntax,
+		}
+	case types.NoVersion, types.AllVersion:
+		return "", "", "", &Error{
+			Input:      s,
+			Offset:     len(before) + 1,
+			Reason:     fmt.Sprintf("%q is reserved", v),
+			Suggestion: tools.Ternary(v == types.AllVersion, before, ""),
+			Err:        EVersion,
 		}
-	} else {
-		return "", "", "", ESyntax
 	}
-
-	return
+	return pl, n, v, nil
 }
 
-func parseOperatorSlash(s string) (
+// parseOperatorSlash parses the platform and name, the part of the input s
+// before the '@'.
+func parseOperatorSlash(s string, before string) (
 	pl types.Platform,
 	n types.ProjectName,
 	err error,
 ) {
-	split := strings.Split(s, "/")
+	platform, name, hasPlatform := strings.Cut(before, "/")
+	if i := strings.Index(name, "/"); hasPlatform && i != -1 {
+		return "", "", &Error{
+			Input:  s,
+			Offset: len(platform) + 1 + i,
+			Reason: "more than one '/'",
+			Err:    ESyntax,
+		}
+	}
 
-	if len(split) == 1 {
+	if !hasPlatform {
+		if platform == "" {
+			return "", "", &Error{Input: s, Reason: "missing package name", Err: ESyntax}
+		}
 		pl = types.AnyPlatform
-		n = types.ProjectName(split[0])
+		n = types.ProjectName(platform)
 		if types.Platform(n).Valid() {
 			// Remember, all platforms are also valid packages under themselves.
 			// This literal is for users to specify the platform itself. See the
@@ -119,15 +217,57 @@ func parseOperatorSlash(s string) (
 			pl = types.Platform(n)
 			n = types.ProjectName(pl)
 		}
-	} else if len(split) == 2 {
-		pl = types.Platform(split[0])
-		if !pl.Valid() {
-			return "", "", EPlatform
+		return pl, n, nil
+	}
+
+	if platform == "" {
+		return "", "", &Error{
+			Input:      s,
+			Reason:     "missing platform before '/'",
+			Suggestion: s[1:],
+			Err:        EPlatform,
 		}
-		n = types.ProjectName(split[1])
-	} else {
-		return "", "", ESyntax
 	}
+	pl = types.Platform(platform)
+	if !pl.Valid() {
+		err := &Error{
+			Input:  s,
+			Reason: fmt.Sprintf("unknown platform %q", platform),
+			Err:    EPlatform,
+		}
+		if suggested := suggestPlatform(platform); suggested != "" {
+			err.Suggestion = string(suggested) + s[len(platform):]
+		}
+		return "", "", err
+	}
+	if name == "" {
+		return "", "", &Error{
+			Input:  s,
+			Offset: len(platform) + 1,
+			Reason: "missing package name",
+			Err:    ESyntax,
+		}
+	}
+	return pl, types.ProjectName(name), nil
+}
 
-	return
+var platforms = []types.Platform{
+	types.Minecraft,
+	types.Fabric,
+	types.Forge,
+	types.Neoforge,
+	types.Mcdr,
+}
+
+// suggestPlatform returns the platform most similar to s, if any is similar
+// enough to be a typo of it.
+func suggestPlatform(s string) types.Platform {
+	var best types.Platform
+	bestScore := 0.8
+	for _, p := range platforms {
+		if score := tools.JaroWinklerSimilarity(s, string(p)); score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
 }
@@ -70,16 +70,19 @@ func ForEachRecursive[T any](arr []any, fn func(T)) {
 }
 
 func IsEmptyVector[T any](arr []T) bool {
-	if len(arr) == 0 {
-		return true
-	}
-	for _, e := range arr {
-		// Use reflection to check if element is a slice
-		v := reflect.ValueOf(e)
-		if v.Kind() == reflect.Slice {
-			if !IsEmptyVector(v.Interface().([]any)) {
-				return false
-			}
+	return isEmptySlice(reflect.ValueOf(arr))
+}
+
+// isEmptySlice reports whether the slice holds no element other than slices,
+// at any depth. Nested slices may be of any type, e.g., [][]int.
+func isEmptySlice(v reflect.Value) bool {
+	for i := 0; i < v.Len(); i++ {
+		e := v.Index(i)
+		if e.Kind() == reflect.Interface {
+			e = e.Elem()
+		}
+		if e.Kind() != reflect.Slice || !isEmptySlice(e) {
+			return false
 		}
 	}
 	return true
@@ -3,6 +3,7 @@ package tools
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -95,6 +96,12 @@ func PrintAsJson(v interface{}) {
 	fmt.Println(string(data))
 }
 
+// IsTerminal reports whether both stdin and stdout are attached to a
+// terminal, i.e., whether interactive prompts can be shown.
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
 func TermWidth() int {
 	width, _, _ := term.GetSize(0)
 	return width
@@ -0,0 +1,563 @@
+// Package browser provides an interactive package browser backed by the charm
+// stack (bubbletea + bubbles + lipgloss).
+//
+// The browser only handles presentation. Searching, fetching project details
+// and listing versions are injected through [Backend], so that this package
+// does not depend on any remote source.
+//
+// Usage:
+//
+//	selections, err := browser.Run("carpet", browser.Backend{...})
+//	if err != nil { ... }
+//	for _, s := range selections { ... }
+package browser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"github.com/muesli/reflow/wrap"
+
+	"lucy/tools"
+	"lucy/types"
+)
+
+// --- Backend ----------------------------------------------------------------
+
+// Backend supplies the data shown in the browser. All functions are called
+// outside the UI goroutine, so they may block on network requests.
+type Backend struct {
+	// Search returns the hits for a query. Warnings are shown in the status
+	// line, while err replaces the result list.
+	Search func(query string) (res types.SearchResults, warnings []error, err error)
+
+	// Details returns the full information of a hit for the detail pane.
+	Details func(hit types.SearchResult) (types.ProjectInformation, error)
+
+	// Versions returns the installable versions of a hit, newest first. The
+	// backend is responsible for filtering out incompatible versions.
+	Versions func(hit types.SearchResult) ([]types.ProjectVersion, error)
+}
+
+// Selection is a package queued for installation.
+type Selection struct {
+	Hit types.SearchResult
+
+	// Version is either a concrete version picked by the user, or
+	// types.LatestCompatibleVersion when the package was quick-queued.
+	Version types.RawVersion
+}
+
+// Run opens the browser with an initial query and blocks until the user
+// confirms or cancels. A cancelled browser returns no selections and no error.
+func Run(query string, backend Backend) (selections []Selection, err error) {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = "search"
+	input.SetValue(query)
+	input.Focus()
+
+	m := model{
+		backend: backend,
+		input:   input,
+		detail:  viewport.New(0, 0),
+		details: make(map[string]string),
+	}
+	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		return nil, err
+	}
+	if fm := final.(model); fm.confirmed {
+		return fm.queue, nil
+	}
+	return nil, nil
+}
+
+// --- bubbletea messages -----------------------------------------------------
+
+type (
+	debounceMsg struct{ seq int }
+	searchMsg   struct {
+		seq      int
+		res      types.SearchResults
+		warnings []error
+		err      error
+	}
+	detailMsg struct {
+		key  string
+		text string
+	}
+	versionsMsg struct {
+		key      string
+		versions []types.ProjectVersion
+		err      error
+	}
+)
+
+// debounce is how long the browser waits after the last keystroke before it
+// searches, so that typing a word does not fire a request per letter.
+const debounce = 300 * time.Millisecond
+
+// --- bubbletea model --------------------------------------------------------
+
+type mode uint8
+
+const (
+	modeList mode = iota
+	modeVersions
+)
+
+type model struct {
+	backend       Backend
+	input         textinput.Model
+	detail        viewport.Model
+	width, height int
+	mode          mode
+
+	// seq identifies the latest query, responses to older queries are dropped
+	seq       int
+	searching bool
+	results   []types.SearchResult
+	warnings  []error
+	err       error
+	cursor    int
+
+	// details caches the rendered detail pane per hit
+	details map[string]string
+
+	versionsFor   string
+	versions      []types.ProjectVersion
+	versionsErr   error
+	versionCursor int
+
+	queue     []Selection
+	confirmed bool
+}
+
+func hitKey(hit types.SearchResult) string {
+	return hit.Source.String() + "/" + hit.Name.String()
+}
+
+func (m model) Init() tea.Cmd {
+	if strings.TrimSpace(m.input.Value()) == "" {
+		return textinput.Blink
+	}
+	return tea.Batch(textinput.Blink, func() tea.Msg { return debounceMsg{seq: 0} })
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.detail.Width = m.paneWidth()
+		m.detail.Height = m.bodyHeight()
+		m.input.Width = m.listWidth() - 3
+		m.syncDetail()
+		return m, nil
+
+	case debounceMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		query := strings.TrimSpace(m.input.Value())
+		if query == "" {
+			m.results, m.warnings, m.err = nil, nil, nil
+			return m, nil
+		}
+		m.searching = true
+		return m, m.search(msg.seq, query)
+
+	case searchMsg:
+		if msg.seq != m.seq {
+			return m, nil
+		}
+		m.searching = false
+		m.results, m.warnings, m.err = msg.res.Results, msg.warnings, msg.err
+		m.cursor = 0
+		m.syncDetail()
+		return m, m.loadDetail()
+
+	case detailMsg:
+		m.details[msg.key] = msg.text
+		m.syncDetail()
+		return m, nil
+
+	case versionsMsg:
+		if msg.key != m.versionsFor {
+			return m, nil
+		}
+		m.versions, m.versionsErr = msg.versions, msg.err
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.mode == modeVersions {
+			return m.updateVersions(msg)
+		}
+		return m.updateList(msg)
+	}
+	return m, nil
+}
+
+func (m model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		return m, tea.Quit
+	case "ctrl+s":
+		m.confirmed = len(m.queue) > 0
+		return m, tea.Quit
+	case "up", "ctrl+p":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		m.syncDetail()
+		return m, m.loadDetail()
+	case "down", "ctrl+n":
+		if m.cursor < len(m.results)-1 {
+			m.cursor++
+		}
+		m.syncDetail()
+		return m, m.loadDetail()
+	case "pgup":
+		m.detail.HalfPageUp()
+		return m, nil
+	case "pgdown":
+		m.detail.HalfPageDown()
+		return m, nil
+	case "tab":
+		if hit, ok := m.current(); ok {
+			m.toggle(Selection{Hit: hit, Version: types.LatestCompatibleVersion})
+		}
+		return m, nil
+	case "enter":
+		hit, ok := m.current()
+		if !ok {
+			return m, nil
+		}
+		m.mode = modeVersions
+		m.versionsFor = hitKey(hit)
+		m.versions, m.versionsErr, m.versionCursor = nil, nil, 0
+		return m, m.loadVersions(hit)
+	}
+
+	before := m.input.Value()
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	if m.input.Value() == before {
+		return m, cmd
+	}
+	m.seq++
+	seq := m.seq
+	return m, tea.Batch(
+		cmd,
+		tea.Tick(debounce, func(time.Time) tea.Msg { return debounceMsg{seq: seq} }),
+	)
+}
+
+func (m model) updateVersions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.mode = modeList
+	case "up", "ctrl+p":
+		if m.versionCursor > 0 {
+			m.versionCursor--
+		}
+	case "down", "ctrl+n":
+		if m.versionCursor < len(m.versions)-1 {
+			m.versionCursor++
+		}
+	case "enter":
+		hit, ok := m.current()
+		if ok && m.versionCursor < len(m.versions) {
+			m.dequeue(hit)
+			m.queue = append(
+				m.queue,
+				Selection{Hit: hit, Version: m.versions[m.versionCursor].Version},
+			)
+			m.mode = modeList
+		}
+	}
+	return m, nil
+}
+
+func (m model) current() (types.SearchResult, bool) {
+	if m.cursor >= len(m.results) {
+		return types.SearchResult{}, false
+	}
+	return m.results[m.cursor], true
+}
+
+// toggle queues s, or removes its hit from the queue if already queued.
+func (m *model) toggle(s Selection) {
+	if !m.dequeue(s.Hit) {
+		m.queue = append(m.queue, s)
+	}
+}
+
+func (m *model) dequeue(hit types.SearchResult) (removed bool) {
+	for i, s := range m.queue {
+		if hitKey(s.Hit) == hitKey(hit) {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (m model) queued(hit types.SearchResult) (Selection, bool) {
+	for _, s := range m.queue {
+		if hitKey(s.Hit) == hitKey(hit) {
+			return s, true
+		}
+	}
+	return Selection{}, false
+}
+
+// --- commands ---------------------------------------------------------------
+
+func (m model) search(seq int, query string) tea.Cmd {
+	search := m.backend.Search
+	return func() tea.Msg {
+		res, warnings, err := search(query)
+		return searchMsg{seq: seq, res: res, warnings: warnings, err: err}
+	}
+}
+
+func (m model) loadDetail() tea.Cmd {
+	hit, ok := m.current()
+	if !ok {
+		return nil
+	}
+	key := hitKey(hit)
+	if _, ok := m.details[key]; ok {
+		return nil
+	}
+	details, width := m.backend.Details, m.paneWidth()
+	return func() tea.Msg {
+		info, err := details(hit)
+		return detailMsg{key: key, text: renderDetail(hit, info, err, width)}
+	}
+}
+
+func (m model) loadVersions(hit types.SearchResult) tea.Cmd {
+	versions, key := m.backend.Versions, hitKey(hit)
+	return func() tea.Msg {
+		v, err := versions(hit)
+		return versionsMsg{key: key, versions: v, err: err}
+	}
+}
+
+// syncDetail points the detail pane at the hit under the cursor.
+func (m *model) syncDetail() {
+	hit, ok := m.current()
+	if !ok {
+		m.detail.SetContent("")
+		return
+	}
+	text, ok := m.details[hitKey(hit)]
+	if !ok {
+		text = tools.Dim("loading...")
+	}
+	m.detail.SetContent(text)
+	m.detail.GotoTop()
+}
+
+// --- view -------------------------------------------------------------------
+
+func (m model) listWidth() int {
+	return max(m.width*2/5, 24)
+}
+
+// paneWidth is the content width of the right pane, excluding its border,
+// margin and padding.
+func (m model) paneWidth() int {
+	return max(m.width-m.listWidth()-4, 20)
+}
+
+// bodyHeight leaves room for the input, status, queue and help lines.
+func (m model) bodyHeight() int {
+	return max(m.height-5, 3)
+}
+
+func (m model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	left := lipgloss.NewStyle().
+		Width(m.listWidth()).
+		Height(m.bodyHeight()).
+		Render(m.viewList())
+	var right string
+	if m.mode == modeVersions {
+		right = m.viewVersions()
+	} else {
+		right = m.detail.View()
+	}
+	right = lipgloss.NewStyle().
+		Width(m.paneWidth()+1).
+		Height(m.bodyHeight()).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		PaddingLeft(1).
+		MarginLeft(1).
+		Render(right)
+
+	var sb strings.Builder
+	sb.WriteString(m.input.View())
+	sb.WriteString("\n")
+	sb.WriteString(m.viewStatus())
+	sb.WriteString("\n")
+	sb.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, left, right))
+	sb.WriteString("\n")
+	sb.WriteString(m.viewQueue())
+	sb.WriteString("\n")
+	sb.WriteString(m.viewHelp())
+	return sb.String()
+}
+
+func (m model) viewStatus() string {
+	switch {
+	case m.searching:
+		return tools.Dim("searching...")
+	case m.err != nil:
+		return tools.Red(m.err.Error())
+	case len(m.warnings) > 0:
+		return tools.Yellow(fmt.Sprintf("%d results, %s", len(m.results), m.warnings[0]))
+	default:
+		return tools.Dim(fmt.Sprintf("%d results", len(m.results)))
+	}
+}
+
+func (m model) viewList() string {
+	width, height := m.listWidth(), m.bodyHeight()
+	start := scrollStart(m.cursor, len(m.results), height)
+	var lines []string
+	for i := start; i < len(m.results) && i < start+height; i++ {
+		hit := m.results[i]
+		marker := tools.Ternary(i == m.cursor, tools.Magenta(">"), " ")
+		check := " "
+		if _, ok := m.queued(hit); ok {
+			check = tools.Green("●")
+		}
+		name := hit.Name.String()
+		if i == m.cursor {
+			name = tools.Bold(name)
+		}
+		line := marker + check + " " + name + " " + tools.Dim(hit.Source.String())
+		lines = append(lines, truncate.StringWithTail(line, uint(width), "…"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m model) viewVersions() string {
+	hit, _ := m.current()
+	title := tools.Bold(tools.Magenta("Versions of " + hit.Name.String()))
+	switch {
+	case m.versionsErr != nil:
+		return title + "\n" + tools.Red(m.versionsErr.Error())
+	case m.versions == nil:
+		return title + "\n" + tools.Dim("loading...")
+	case len(m.versions) == 0:
+		return title + "\n" + tools.Dim("no compatible versions")
+	}
+
+	height := m.bodyHeight() - 1
+	start := scrollStart(m.versionCursor, len(m.versions), height)
+	lines := []string{title}
+	for i := start; i < len(m.versions) && i < start+height; i++ {
+		v := m.versions[i]
+		marker := tools.Ternary(i == m.versionCursor, tools.Magenta(">"), " ")
+		version := v.Version.String()
+		if i == m.versionCursor {
+			version = tools.Bold(version)
+		}
+		line := marker + " " + version
+		if v.Prerelease {
+			line += " " + tools.Yellow("pre")
+		}
+		if len(v.GameVersions) > 0 {
+			line += " " + tools.Dim(v.GameVersions[len(v.GameVersions)-1].String())
+		}
+		if !v.Published.IsZero() {
+			line += " " + tools.Dim(v.Published.Format(time.DateOnly))
+		}
+		lines = append(lines, truncate.StringWithTail(line, uint(m.paneWidth()), "…"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m model) viewQueue() string {
+	if len(m.queue) == 0 {
+		return tools.Dim("queue is empty")
+	}
+	var items []string
+	for _, s := range m.queue {
+		items = append(items, s.Hit.Name.String()+"@"+s.Version.String())
+	}
+	line := tools.Bold("Queue ") + strings.Join(items, ", ")
+	return truncate.StringWithTail(line, uint(m.width), "…")
+}
+
+func (m model) viewHelp() string {
+	if m.mode == modeVersions {
+		return tools.Dim("↑/↓ move • enter queue version • esc back")
+	}
+	return tools.Dim(
+		"↑/↓ move • enter pick version • tab quick queue • pgup/pgdn scroll • ctrl+s install • esc quit",
+	)
+}
+
+// --- helpers ----------------------------------------------------------------
+
+// scrollStart returns the first visible row so that the cursor stays within
+// a window of height rows.
+func scrollStart(cursor, total, height int) int {
+	if total <= height || cursor < height/2 {
+		return 0
+	}
+	return min(cursor-height/2, total-height)
+}
+
+func renderDetail(
+	hit types.SearchResult,
+	info types.ProjectInformation,
+	err error,
+	width int,
+) string {
+	var sb strings.Builder
+	title := tools.Ternary(info.Title != "", info.Title, hit.Title)
+	brief := tools.Ternary(info.Brief != "", info.Brief, hit.Brief)
+	sb.WriteString(tools.Bold(tools.Magenta(title)))
+	sb.WriteString("\n")
+	if brief != "" {
+		sb.WriteString(wrap.String(brief, width))
+		sb.WriteString("\n")
+	}
+	if len(hit.Authors) > 0 {
+		sb.WriteString(tools.Dim("by " + strings.Join(hit.Authors, ", ")))
+		sb.WriteString("\n")
+	}
+	if len(hit.Labels) > 0 {
+		sb.WriteString(tools.Dim(strings.Join(hit.Labels, ", ")))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+
+	switch {
+	case err != nil:
+		sb.WriteString(tools.Red(wrap.String(err.Error(), width)))
+	case info.DescriptionIsMarkdown:
+		sb.WriteString(tools.MarkdownToAnsi(info.Description, width))
+	default:
+		sb.WriteString(wrap.String(info.Description, width))
+	}
+	return sb.String()
+}
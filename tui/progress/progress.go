@@ -250,9 +250,9 @@ func (r *proxyReader) Read(p []byte) (int, error) {
 	if r.total > 0 {
 		r.tracker.SetPercent(float64(r.read) / float64(r.total))
 		r.tracker.SetMessage(fmt.Sprintf("%s / %s",
-			humanBytes(r.read), humanBytes(r.total)))
+			tools.HumanBytes(r.read), tools.HumanBytes(r.total)))
 	} else {
-		r.tracker.SetMessage(humanBytes(r.read))
+		r.tracker.SetMessage(tools.HumanBytes(r.read))
 	}
 	return n, err
 }
@@ -262,16 +262,3 @@ func (r *proxyReader) Read(p []byte) (int, error) {
 func clamp01(v float64) float64 {
 	return math.Max(0, math.Min(1, v))
 }
-
-func humanBytes(b int64) string {
-	const unit = 1024
-	if b < unit {
-		return fmt.Sprintf("%d B", b)
-	}
-	div, exp := int64(unit), 0
-	for n := b / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
-}
@@ -0,0 +1,391 @@
+package progress
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"golang.org/x/term"
+
+	"lucy/tools"
+)
+
+// --- Status -----------------------------------------------------------------
+
+// Status is the phase a task in a [Group] is in.
+type Status uint8
+
+const (
+	StatusQueued Status = iota
+	StatusDownloading
+	StatusVerifying
+	StatusExtracting
+	StatusDone
+	StatusFailed
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusQueued:
+		return "queued"
+	case StatusDownloading:
+		return "downloading"
+	case StatusVerifying:
+		return "verifying"
+	case StatusExtracting:
+		return "extracting"
+	case StatusDone:
+		return "done"
+	case StatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// --- Group ------------------------------------------------------------------
+
+// Group tracks many tasks at once, showing one bar per task and an overall
+// bar summing them up.
+//
+// Tasks are identified by caller-chosen integers. Group has the same methods
+// as util.DownloadReporter, so it can be plugged into util.Downloader directly.
+//
+// When stdout is not a terminal or styles are turned off, Group falls back to
+// printing a plain line on every status change, so that CI logs stay readable.
+//
+// Usage:
+//
+//	g := progress.NewGroup("Downloading")
+//	go func() {
+//	    defer g.Close()
+//	    downloader.Reporter = g
+//	    downloader.Download(ctx, tasks)
+//	}()
+//	if err := g.Run(); err != nil { ... }
+type Group struct {
+	title string
+	plain bool
+	out   io.Writer
+
+	mu    sync.Mutex
+	order []int
+	tasks map[int]*task
+
+	program *tea.Program
+	closed  chan struct{}
+	once    sync.Once
+}
+
+type task struct {
+	name    string
+	total   int64
+	written int64
+	status  Status
+	err     error
+
+	// rate is a moving average in bytes per second, sampled on every refresh
+	rate        float64
+	lastWritten int64
+	lastSample  time.Time
+
+	// step is the last quarter printed in plain mode
+	step int64
+}
+
+// NewGroup creates a [Group]. Call [Group.Run] to display it.
+func NewGroup(title string) *Group {
+	return &Group{
+		title:  title,
+		plain:  !tools.StylesEnabled() || !term.IsTerminal(int(os.Stdout.Fd())),
+		out:    os.Stdout,
+		tasks:  make(map[int]*task),
+		closed: make(chan struct{}),
+	}
+}
+
+// ErrInterrupted is returned by [Group.Run] when the user pressed Ctrl+C.
+var ErrInterrupted = errors.New("interrupted")
+
+// Run displays the group and blocks until [Group.Close] is called or the user
+// presses Ctrl+C, then it returns [ErrInterrupted].
+func (g *Group) Run() error {
+	if g.plain {
+		<-g.closed
+		return nil
+	}
+	g.program = tea.NewProgram(groupModel{group: g, bar: newBar()})
+	go func() {
+		<-g.closed
+		g.program.Send(closeMsg{})
+	}()
+	final, err := g.program.Run()
+	if err != nil {
+		return err
+	}
+	if m, ok := final.(groupModel); ok && m.interrupted {
+		return ErrInterrupted
+	}
+	return nil
+}
+
+// Close stops the display. It is safe to call more than once.
+func (g *Group) Close() {
+	g.once.Do(func() { close(g.closed) })
+}
+
+// Queue registers a task before it starts, so that it shows up as queued.
+func (g *Group) Queue(id int, name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.get(id).name = name
+}
+
+// Start marks a task as downloading. total is -1 if unknown.
+func (g *Group) Start(id int, name string, total int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t := g.get(id)
+	t.name, t.total, t.written, t.step = name, total, 0, 0
+	t.lastWritten, t.lastSample = 0, time.Now()
+	g.setStatus(id, t, StatusDownloading)
+}
+
+// Progress sets the number of bytes a task has transferred.
+func (g *Group) Progress(id int, written int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t := g.get(id)
+	t.written = written
+	if g.plain && t.total > 0 {
+		if step := written * 4 / t.total; step > t.step && step < 4 {
+			t.step = step
+			g.printf("%s %s %d%%", t.status, t.name, step*25)
+		}
+	}
+}
+
+func (g *Group) Verifying(id int) {
+	g.SetStatus(id, StatusVerifying)
+}
+
+func (g *Group) Retry(id int, attempt int, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t := g.get(id)
+	t.err = err
+	g.printf("retrying %s (%d): %s", g.nameOf(id), attempt, err)
+}
+
+// Done marks a task as done, or failed if err is not nil.
+func (g *Group) Done(id int, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t := g.get(id)
+	t.err = err
+	g.setStatus(id, t, tools.Ternary(err == nil, StatusDone, StatusFailed))
+}
+
+// SetStatus moves a task to another phase, e.g., StatusExtracting.
+func (g *Group) SetStatus(id int, s Status) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.setStatus(id, g.get(id), s)
+}
+
+func (g *Group) setStatus(id int, t *task, s Status) {
+	if t.status == s {
+		return
+	}
+	t.status = s
+	if s != StatusFailed {
+		t.err = nil
+	}
+	switch {
+	case s == StatusFailed:
+		g.printf("failed %s: %s", g.nameOf(id), t.err)
+	default:
+		g.printf("%s %s", s, g.nameOf(id))
+	}
+}
+
+// printf only prints in plain mode. The caller must hold the lock.
+func (g *Group) printf(format string, a ...any) {
+	if g.plain {
+		_, _ = fmt.Fprintf(g.out, format+"\n", a...)
+	}
+}
+
+// get returns the task, creating it if necessary. The caller must hold the
+// lock.
+func (g *Group) get(id int) *task {
+	t, ok := g.tasks[id]
+	if !ok {
+		t = &task{total: -1}
+		g.tasks[id] = t
+		g.order = append(g.order, id)
+	}
+	return t
+}
+
+func (g *Group) nameOf(id int) string {
+	if name := g.tasks[id].name; name != "" {
+		return name
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+// snapshot samples the transfer rates and copies the tasks for rendering.
+func (g *Group) snapshot() (tasks []task) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	for _, id := range g.order {
+		t := g.tasks[id]
+		if t.status == StatusDownloading && !t.lastSample.IsZero() {
+			if elapsed := now.Sub(t.lastSample).Seconds(); elapsed > 0 {
+				current := float64(t.written-t.lastWritten) / elapsed
+				t.rate = tools.Ternary(t.rate == 0, current, 0.7*t.rate+0.3*current)
+			}
+		}
+		t.lastWritten, t.lastSample = t.written, now
+		snap := *t
+		if snap.name == "" {
+			snap.name = g.nameOf(id)
+		}
+		tasks = append(tasks, snap)
+	}
+	return tasks
+}
+
+// --- bubbletea model --------------------------------------------------------
+
+const refreshInterval = 200 * time.Millisecond
+
+type tickMsg time.Time
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func newBar() progress.Model {
+	return progress.New(progress.WithDefaultGradient(), progress.WithoutPercentage())
+}
+
+type groupModel struct {
+	group *Group
+	bar   progress.Model
+	tasks []task
+	width int
+	quit  bool
+	// interrupted is set when the user pressed Ctrl+C
+	interrupted bool
+}
+
+func (m groupModel) Init() tea.Cmd { return tick() }
+
+func (m groupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+	case tickMsg:
+		m.tasks = m.group.snapshot()
+		return m, tick()
+	case closeMsg:
+		m.tasks = m.group.snapshot()
+		m.quit = true
+		return m, tea.Quit
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.interrupted = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+func (m groupModel) View() string {
+	width := tools.Ternary(m.width > 0, m.width, 80)
+	nameWidth := min(max(width/4, 12), 32)
+	m.bar.Width = max(width-nameWidth-36, 10)
+
+	var sb strings.Builder
+	var written, total int64
+	var done, failed int
+	for _, t := range m.tasks {
+		// Tasks of unknown size cannot contribute to the overall ratio
+		if t.total > 0 {
+			written += t.written
+			total += t.total
+		}
+		switch t.status {
+		case StatusDone:
+			done++
+		case StatusFailed:
+			failed++
+		}
+	}
+
+	// Overall summary, styled like the title of the single bar Tracker
+	title := tools.Bold(tools.Magenta(m.group.title))
+	sb.WriteString(title)
+	sb.WriteString(strings.Repeat(" ", max(nameWidth-lipgloss.Width(title), 0)+2))
+	sb.WriteString(m.bar.ViewAs(ratio(written, total)))
+	summary := fmt.Sprintf("  %d/%d done", done, len(m.tasks))
+	if failed > 0 {
+		summary += tools.Red(fmt.Sprintf(", %d failed", failed))
+	}
+	sb.WriteString(summary)
+	sb.WriteString("\n")
+
+	for _, t := range m.tasks {
+		name := truncate.StringWithTail(t.name, uint(nameWidth), "…")
+		sb.WriteString(name)
+		sb.WriteString(strings.Repeat(" ", nameWidth-lipgloss.Width(name)+2))
+		percent := ratio(t.written, t.total)
+		if t.status == StatusDone {
+			percent = 1
+		}
+		sb.WriteString(m.bar.ViewAs(percent))
+		sb.WriteString("  ")
+		sb.WriteString(statusLabel(t))
+		sb.WriteString("\n")
+	}
+	if m.quit {
+		return sb.String()
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func statusLabel(t task) string {
+	switch t.status {
+	case StatusDownloading:
+		label := fmt.Sprintf("%3.0f%%", ratio(t.written, t.total)*100)
+		if t.total <= 0 {
+			label = tools.HumanBytes(t.written)
+		}
+		return label + tools.Dim(fmt.Sprintf(" %s/s", tools.HumanBytes(int64(t.rate))))
+	case StatusFailed:
+		return tools.Red("failed")
+	case StatusDone:
+		return tools.Green("done")
+	default:
+		return tools.Dim(t.status.String())
+	}
+}
+
+func ratio(a, b int64) float64 {
+	if b <= 0 {
+		return 0
+	}
+	return clamp01(float64(a) / float64(b))
+}
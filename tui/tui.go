@@ -17,6 +17,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
 	"github.com/muesli/reflow/wrap"
 
 	"lucy/tools"
@@ -502,6 +504,96 @@ func (f *FieldCheckBox) Render() string {
 	return sb.String()
 }
 
+// FieldTable renders rows of cells in aligned columns below a header row.
+// Cells may contain styled text, column widths are measured visually.
+//
+// SubRows, if set, holds an optional dimmed line shown below each row, e.g.,
+// a description. MaxLines limits the number of rows (not lines) shown, 0 for
+// unlimited. MaxCellWidth truncates long cells, 0 for no limit.
+type FieldTable struct {
+	Headers      []string
+	Rows         [][]string
+	SubRows      []string
+	MaxLines     int
+	MaxCellWidth int
+}
+
+func (f *FieldTable) KeyLength() int {
+	return 0
+}
+
+func (f *FieldTable) Render() string {
+	if len(f.Rows) == 0 {
+		return ""
+	}
+
+	cell := func(s string) string {
+		if f.MaxCellWidth > 0 && lipgloss.Width(s) > f.MaxCellWidth {
+			return truncate.StringWithTail(s, uint(f.MaxCellWidth), "…")
+		}
+		return s
+	}
+
+	widths := make([]int, len(f.Headers))
+	for i, h := range f.Headers {
+		widths[i] = lipgloss.Width(h)
+	}
+	for _, row := range f.Rows {
+		for i, c := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], lipgloss.Width(cell(c)))
+			}
+		}
+	}
+
+	writeRow := func(sb *strings.Builder, row []string, style func(any) string) {
+		for i, c := range row {
+			if i >= len(widths) {
+				break
+			}
+			c = cell(c)
+			if style != nil {
+				c = style(c)
+			}
+			sb.WriteString(c)
+			if i != len(row)-1 {
+				sb.WriteString(strings.Repeat(" ", widths[i]-lipgloss.Width(c)+2))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	var sb strings.Builder
+	writeRow(&sb, f.Headers, func(v any) string { return tools.Bold(tools.Magenta(v)) })
+	for i, row := range f.Rows {
+		if f.MaxLines != 0 && i == f.MaxLines {
+			sb.WriteString(
+				renderDim(
+					fmt.Sprintf(
+						"(%d more, use --long to show all)",
+						len(f.Rows)-i,
+					),
+				),
+			)
+			sb.WriteString("\n")
+			break
+		}
+		writeRow(&sb, row, nil)
+		if i < len(f.SubRows) && f.SubRows[i] != "" {
+			sub := f.SubRows[i]
+			if width := tools.TermWidth(); width > 4 {
+				sub = wrap.String(sub, width-4)
+			}
+			for _, line := range strings.Split(sub, "\n") {
+				sb.WriteString("  ")
+				sb.WriteString(renderDim(line))
+				sb.WriteString("\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
 // Flush renders all fields in data and prints the composed output.
 func Flush(data *Data) {
 	for _, field := range data.Fields {
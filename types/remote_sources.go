@@ -1,6 +1,10 @@
 package types
 
-import "strings"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 type Source uint8
 
@@ -43,6 +47,15 @@ func (s Source) Title() string {
 	}
 }
 
+// MarshalJSON writes the source by its name, as accepted by --source. Merged
+// results are "auto".
+func (s Source) MarshalJSON() ([]byte, error) {
+	if s == AutoSource {
+		return json.Marshal("auto")
+	}
+	return json.Marshal(s.String())
+}
+
 var stringToSourceMap = map[string]Source{
 	"auto":       AutoSource,
 	"":           AutoSource,
@@ -98,7 +111,42 @@ func (i SearchIndex) ToModrinth() string {
 	}
 }
 
+// SearchResults is a list of hits from one or more sources. Source is
+// AutoSource when the results are merged from multiple sources, in which case
+// each SearchResult carries its own origin.
 type SearchResults struct {
-	Source  Source
-	Results []ProjectName
+	Source  Source         `json:"source"`
+	Results []SearchResult `json:"results"`
+}
+
+// SearchResult is a single hit in SearchResults. Apart from Name and Source,
+// every field is optional as not all sources provide them in their search
+// APIs.
+type SearchResult struct {
+	Name         ProjectName  `json:"name"`
+	Source       Source       `json:"source"`
+	Title        string       `json:"title,omitempty"`
+	Brief        string       `json:"brief,omitempty"`
+	Authors      []string     `json:"authors,omitempty"`
+	Labels       []string     `json:"labels,omitempty"`
+	Downloads    int          `json:"downloads"`
+	GameVersions []RawVersion `json:"game_versions,omitempty"`
+	ClientSide   SideSupport  `json:"client_side,omitempty"`
+	ServerSide   SideSupport  `json:"server_side,omitempty"`
+	Updated      time.Time    `json:"updated"`
+}
+
+// SideSupport describes whether a project runs on the client or the server.
+// The values follow Modrinth's convention.
+type SideSupport string
+
+const (
+	SideUnknown     SideSupport = ""
+	SideRequired    SideSupport = "required"
+	SideOptional    SideSupport = "optional"
+	SideUnsupported SideSupport = "unsupported"
+)
+
+func (s SideSupport) Supported() bool {
+	return s == SideRequired || s == SideOptional
 }
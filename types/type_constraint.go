@@ -0,0 +1,396 @@
+package types
+
+import (
+	"slices"
+)
+
+// The algebra of version constraint expressions. An expression is in
+// disjunctive normal form, the OR of ANDs. An expression without constraints,
+// or with an empty AND, is any version, and noVersion is none.
+//
+// AND groups are simplified into intervals. Groups whose versions cannot be
+// compared with each other, e.g., of different schemes, are kept as they
+// are, and taken as satisfiable.
+
+var noVersion = VersionConstraintExpression{{{Operator: OpNone}}}
+
+func (exps VersionConstraintExpression) isAny() bool {
+	return len(exps) == 0 || slices.ContainsFunc(
+		exps,
+		func(and []VersionConstraint) bool { return len(and) == 0 },
+	)
+}
+
+// Negate returns the expression no version satisfying exps satisfies, and
+// the other way round. NOT((a AND b) OR c) is (NOT a OR NOT b) AND NOT c,
+// which is then distributed back into an OR of ANDs.
+func (exps VersionConstraintExpression) Negate() VersionConstraintExpression {
+	if exps.isAny() {
+		return noVersion
+	}
+	negated := VersionConstraintExpression{{}}
+	for _, and := range exps {
+		var or VersionConstraintExpression
+		for _, c := range and {
+			or = append(or, c.Negate()...)
+		}
+		negated = negated.product(or)
+	}
+	return negated.Simplify()
+}
+
+// Negate returns the constraint any version not satisfying c satisfies. The
+// weak operators stand for ranges, so their negation is the OR of two.
+func (c VersionConstraint) Negate() VersionConstraintExpression {
+	single := func(op VersionOperator, v ComparableVersion) VersionConstraintExpression {
+		return VersionConstraintExpression{{{Value: v, Operator: op}}}
+	}
+	switch c.Operator {
+	case OpEq:
+		return single(OpNeq, c.Value)
+	case OpNeq:
+		return single(OpEq, c.Value)
+	case OpGt:
+		return single(OpLte, c.Value)
+	case OpGte:
+		return single(OpLt, c.Value)
+	case OpLt:
+		return single(OpGte, c.Value)
+	case OpLte:
+		return single(OpGt, c.Value)
+	case OpWeakEq:
+		return VersionConstraintExpression{
+			{{Value: c.Value, Operator: OpLt}},
+			{{Value: c.Value.Next(2), Operator: OpGte}},
+		}
+	case OpWeakGt:
+		return VersionConstraintExpression{
+			{{Value: c.Value, Operator: OpLt}},
+			{{Value: c.Value.Next(1), Operator: OpGte}},
+		}
+	default: // OpNone
+		return VersionConstraintExpression{{}}
+	}
+}
+
+// Intersect returns the expression of the versions satisfying both.
+func (exps VersionConstraintExpression) Intersect(other VersionConstraintExpression) VersionConstraintExpression {
+	switch {
+	case exps.isAny():
+		return other.Simplify()
+	case other.isAny():
+		return exps.Simplify()
+	}
+	return exps.product(other).Simplify()
+}
+
+// product distributes AND over OR, without simplifying.
+func (exps VersionConstraintExpression) product(other VersionConstraintExpression) VersionConstraintExpression {
+	var result VersionConstraintExpression
+	for _, a := range exps {
+		for _, b := range other {
+			result = append(result, append(slices.Clone(a), b...))
+		}
+	}
+	return result
+}
+
+// Union returns the expression of the versions satisfying either.
+func (exps VersionConstraintExpression) Union(other VersionConstraintExpression) VersionConstraintExpression {
+	if exps.isAny() || other.isAny() {
+		return nil
+	}
+	return append(slices.Clone(exps), other...).Simplify()
+}
+
+// IsEmpty tells whether no version satisfies the expression.
+func (exps VersionConstraintExpression) IsEmpty() bool {
+	simplified := exps.Simplify()
+	return len(simplified) == 1 && len(simplified[0]) == 1 && simplified[0][0].Operator == OpNone
+}
+
+// Simplify returns an equivalent expression with every AND group reduced to
+// at most a lower bound, an upper bound, and the versions excluded between
+// them. Groups no version satisfies are dropped, and overlapping or adjacent
+// ranges are merged. An expression any version satisfies is nil.
+func (exps VersionConstraintExpression) Simplify() VersionConstraintExpression {
+	if exps.isAny() {
+		return nil
+	}
+	var intervals []interval
+	var kept VersionConstraintExpression
+	for _, and := range exps {
+		in, ok := newInterval(and)
+		switch {
+		case !ok:
+			kept = append(kept, slices.Clone(and))
+		case !in.empty():
+			intervals = append(intervals, in)
+		}
+	}
+	intervals = mergeIntervals(intervals)
+
+	var result VersionConstraintExpression
+	for _, in := range intervals {
+		and := in.constraints()
+		if len(and) == 0 {
+			return nil
+		}
+		result = append(result, and)
+	}
+	result = append(result, kept...)
+	if len(result) == 0 {
+		return noVersion
+	}
+	return result
+}
+
+// interval is an AND group as bounds. A bound with no operator is unbounded.
+type interval struct {
+	lower, upper VersionConstraint // OpGt or OpGte, OpLt or OpLte
+	excluded     []ComparableVersion
+	none         bool
+}
+
+const unbounded = OpNone
+
+// newInterval reduces the AND group to an interval. It fails if the versions
+// in the group cannot be compared with each other.
+func newInterval(and []VersionConstraint) (in interval, ok bool) {
+	for i, c := range and {
+		if c.Operator == OpNone {
+			continue
+		}
+		if c.Value.Scheme == Invalid {
+			return interval{}, false
+		}
+		for _, d := range and[:i] {
+			if d.Operator != OpNone && !c.Value.Comparable(d.Value) {
+				return interval{}, false
+			}
+		}
+	}
+
+	in.lower.Operator, in.upper.Operator = unbounded, unbounded
+	raise := func(c VersionConstraint) {
+		if in.lower.Operator == unbounded || c.Value.Gt(in.lower.Value) ||
+			(c.Value.Eq(in.lower.Value) && c.Operator == OpGt) {
+			in.lower = c
+		}
+	}
+	lower := func(c VersionConstraint) {
+		if in.upper.Operator == unbounded || c.Value.Lt(in.upper.Value) ||
+			(c.Value.Eq(in.upper.Value) && c.Operator == OpLt) {
+			in.upper = c
+		}
+	}
+	for _, c := range and {
+		switch c.Operator {
+		case OpNone:
+			in.none = true
+		case OpEq:
+			raise(VersionConstraint{Value: c.Value, Operator: OpGte})
+			lower(VersionConstraint{Value: c.Value, Operator: OpLte})
+		case OpNeq:
+			in.excluded = append(in.excluded, c.Value)
+		case OpGt, OpGte:
+			raise(c)
+		case OpLt, OpLte:
+			lower(c)
+		case OpWeakEq:
+			raise(VersionConstraint{Value: c.Value, Operator: OpGte})
+			lower(VersionConstraint{Value: c.Value.Next(2), Operator: OpLt})
+		case OpWeakGt:
+			raise(VersionConstraint{Value: c.Value, Operator: OpGte})
+			lower(VersionConstraint{Value: c.Value.Next(1), Operator: OpLt})
+		}
+	}
+	// Only the excluded versions within the bounds matter
+	in.excluded = slices.DeleteFunc(
+		in.excluded,
+		func(v ComparableVersion) bool { return !in.contains(v) },
+	)
+	in.excluded = slices.CompactFunc(
+		in.sortedExcluded(),
+		func(a, b ComparableVersion) bool { return a.Eq(b) },
+	)
+	return in, true
+}
+
+func (in interval) contains(v ComparableVersion) bool {
+	return (in.lower.Operator == unbounded || in.lower.Operator.Comparator()(v, in.lower.Value)) &&
+		(in.upper.Operator == unbounded || in.upper.Operator.Comparator()(v, in.upper.Value))
+}
+
+func (in interval) sortedExcluded() []ComparableVersion {
+	return slices.SortedFunc(
+		slices.Values(in.excluded),
+		func(a, b ComparableVersion) int { return a.compare(b) },
+	)
+}
+
+// single tells whether the interval is a single version, which is then its
+// lower bound.
+func (in interval) single() bool {
+	return in.lower.Operator == OpGte && in.upper.Operator == OpLte &&
+		in.lower.Value.Eq(in.upper.Value)
+}
+
+func (in interval) empty() bool {
+	if in.none {
+		return true
+	}
+	if in.lower.Operator == unbounded || in.upper.Operator == unbounded {
+		return false
+	}
+	if in.lower.Value.Gt(in.upper.Value) {
+		return true
+	}
+	if in.lower.Value.Eq(in.upper.Value) {
+		return !in.single() || len(in.excluded) != 0
+	}
+	return false
+}
+
+func (in interval) constraints() []VersionConstraint {
+	if in.single() {
+		return []VersionConstraint{{Value: in.lower.Value, Operator: OpEq}}
+	}
+	var and []VersionConstraint
+	if in.lower.Operator != unbounded {
+		and = append(and, in.lower)
+	}
+	if in.upper.Operator != unbounded {
+		and = append(and, in.upper)
+	}
+	for _, v := range in.excluded {
+		and = append(and, VersionConstraint{Value: v, Operator: OpNeq})
+	}
+	return and
+}
+
+// mergeIntervals merges the intervals that overlap or are adjacent, and sorts
+// them by their lower bounds. Intervals not comparable with each other are
+// left apart.
+func mergeIntervals(intervals []interval) []interval {
+	comparable := func(a, b interval) bool {
+		for _, x := range []VersionConstraint{a.lower, a.upper} {
+			for _, y := range []VersionConstraint{b.lower, b.upper} {
+				if x.Operator != unbounded && y.Operator != unbounded && !x.Value.Comparable(y.Value) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for i := 0; i < len(intervals); i++ {
+		for j := 0; j < len(intervals); j++ {
+			if i == j || !comparable(intervals[i], intervals[j]) {
+				continue
+			}
+			merged, ok := union(intervals[i], intervals[j])
+			if !ok {
+				continue
+			}
+			intervals[i] = merged
+			intervals = slices.Delete(intervals, j, j+1)
+			// Start over, the merged interval may now touch others
+			i, j = -1, len(intervals)
+		}
+	}
+	slices.SortStableFunc(
+		intervals,
+		func(a, b interval) int {
+			if !comparable(a, b) {
+				return 0
+			}
+			return compareLower(a.lower, b.lower)
+		},
+	)
+	return intervals
+}
+
+// union merges two intervals into one, if their union is an interval. The
+// versions either one excludes stay excluded unless the other includes them,
+// and a gap of a single version becomes an excluded one, e.g., <1.0.0 and
+// >1.0.0 merge into !=1.0.0.
+func union(a, b interval) (interval, bool) {
+	if compareLower(a.lower, b.lower) > 0 {
+		a, b = b, a
+	}
+	// a starts first, b must start before or right where a ends
+	var gap []ComparableVersion
+	if a.upper.Operator != unbounded && b.lower.Operator != unbounded {
+		if b.lower.Value.Gt(a.upper.Value) {
+			return interval{}, false
+		}
+		if b.lower.Value.Eq(a.upper.Value) && a.upper.Operator == OpLt && b.lower.Operator == OpGt {
+			gap = append(gap, a.upper.Value)
+		}
+	}
+	merged := a
+	if compareUpper(b.upper, a.upper) > 0 {
+		merged.upper = b.upper
+	}
+	merged.excluded = gap
+	for _, v := range append(slices.Clone(a.excluded), b.excluded...) {
+		if !a.has(v) && !b.has(v) {
+			merged.excluded = append(merged.excluded, v)
+		}
+	}
+	merged.excluded = slices.CompactFunc(
+		merged.sortedExcluded(),
+		func(a, b ComparableVersion) bool { return a.Eq(b) },
+	)
+	return merged, true
+}
+
+// has tells whether the version is in the interval, and not excluded.
+func (in interval) has(v ComparableVersion) bool {
+	return in.contains(v) && !slices.ContainsFunc(in.excluded, v.Eq)
+}
+
+// compareLower orders lower bounds, unbounded first.
+func compareLower(a, b VersionConstraint) int {
+	switch {
+	case a.Operator == unbounded && b.Operator == unbounded:
+		return 0
+	case a.Operator == unbounded:
+		return -1
+	case b.Operator == unbounded:
+		return 1
+	}
+	if c := a.Value.compare(b.Value); c != 0 {
+		return c
+	}
+	// >=v starts before >v
+	return boolCompare(a.Operator == OpGt, b.Operator == OpGt)
+}
+
+// compareUpper orders upper bounds, unbounded last.
+func compareUpper(a, b VersionConstraint) int {
+	switch {
+	case a.Operator == unbounded && b.Operator == unbounded:
+		return 0
+	case a.Operator == unbounded:
+		return 1
+	case b.Operator == unbounded:
+		return -1
+	}
+	if c := a.Value.compare(b.Value); c != 0 {
+		return c
+	}
+	// <v ends before <=v
+	return boolCompare(a.Operator == OpLte, b.Operator == OpLte)
+}
+
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
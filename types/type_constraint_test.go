@@ -0,0 +1,92 @@
+package types
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// The properties below are checked on random expressions and versions. The
+// versions are kept to a few small numbers and prereleases, so that they often
+// hit the bounds of the constraints.
+
+var testPrereleases = []string{"", "", "0", "alpha", "beta.1"}
+
+func randomVersion(r *rand.Rand) ComparableVersion {
+	return ComparableVersion{
+		Scheme:     Semver,
+		Major:      r.Uint64N(3),
+		Minor:      r.Uint64N(3),
+		Patch:      r.Uint64N(3),
+		Prerelease: testPrereleases[r.IntN(len(testPrereleases))],
+	}
+}
+
+func randomConstraint(r *rand.Rand) VersionConstraint {
+	// OpNone is rare, or most groups would be empty
+	if r.IntN(20) == 0 {
+		return VersionConstraint{Operator: OpNone}
+	}
+	return VersionConstraint{Value: randomVersion(r), Operator: VersionOperator(r.IntN(int(OpNone)))}
+}
+
+func randomExpression(r *rand.Rand) VersionConstraintExpression {
+	exps := make(VersionConstraintExpression, r.IntN(4))
+	for i := range exps {
+		exps[i] = make([]VersionConstraint, r.IntN(4))
+		for j := range exps[i] {
+			exps[i][j] = randomConstraint(r)
+		}
+	}
+	return exps
+}
+
+// checkProperty runs check on random expressions, each against random
+// versions.
+func checkProperty(
+	t *testing.T,
+	check func(e1, e2 VersionConstraintExpression, v ComparableVersion) bool,
+) {
+	t.Helper()
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 2000 {
+		e1, e2 := randomExpression(r), randomExpression(r)
+		for range 20 {
+			v := randomVersion(r)
+			if !check(e1, e2, v) {
+				t.Fatalf("fails for %q and %q with %s", e1, e2, v)
+			}
+		}
+	}
+}
+
+func TestConstraintNegate(t *testing.T) {
+	checkProperty(
+		t, func(e, _ VersionConstraintExpression, v ComparableVersion) bool {
+			return e.Negate().Contains(v) == !e.Contains(v)
+		},
+	)
+}
+
+func TestConstraintIntersect(t *testing.T) {
+	checkProperty(
+		t, func(e1, e2 VersionConstraintExpression, v ComparableVersion) bool {
+			return e1.Intersect(e2).Contains(v) == (e1.Contains(v) && e2.Contains(v))
+		},
+	)
+}
+
+func TestConstraintUnion(t *testing.T) {
+	checkProperty(
+		t, func(e1, e2 VersionConstraintExpression, v ComparableVersion) bool {
+			return e1.Union(e2).Contains(v) == (e1.Contains(v) || e2.Contains(v))
+		},
+	)
+}
+
+func TestConstraintSimplify(t *testing.T) {
+	checkProperty(
+		t, func(e, _ VersionConstraintExpression, v ComparableVersion) bool {
+			return e.Simplify().Contains(v) == e.Contains(v)
+		},
+	)
+}
@@ -1,9 +1,9 @@
 package types
 
 import (
+	"fmt"
+	"slices"
 	"strings"
-
-	"lucy/tools"
 )
 
 // RawVersion is the version of a package. Here we expect mods and plugins
@@ -34,6 +34,22 @@ func (v RawVersion) NeedsInfer() bool {
 	case AllVersion, NoVersion, UnknownVersion, LatestVersion, LatestCompatibleVersion:
 		return true
 	}
+	return v.IsRange()
+}
+
+// IsRange tells whether the version is a range of versions rather than a
+// single one, e.g., ^0.5, ">=1.4.100 <1.5" or 1.20.x. A range is inferred to
+// the best version matching it.
+func (v RawVersion) IsRange() bool {
+	if strings.ContainsAny(string(v), "<>=^~|* ") {
+		return true
+	}
+	core, _, _ := strings.Cut(string(v), "-")
+	for _, component := range strings.Split(core, ".") {
+		if component == "x" || component == "X" {
+			return true
+		}
+	}
 	return false
 }
 
@@ -46,15 +62,37 @@ var (
 )
 
 func (p1 ComparableVersion) schemeMatch(p2 ComparableVersion) bool {
+	if p1.Scheme.IsMinecraft() && p2.Scheme.IsMinecraft() {
+		_, ok := compareMinecraft(p1, p2)
+		return ok
+	}
 	return p1.Scheme == p2.Scheme
 }
 
-// Eq checks whether p1 is equal to p2.
+// Comparable tells whether p1 can be compared with p2. Comparisons of versions
+// that are not are always false.
+func (p1 ComparableVersion) Comparable(p2 ComparableVersion) bool {
+	return p1.schemeMatch(p2)
+}
+
+// compare orders p1 and p2, which must be comparable.
+func (p1 ComparableVersion) compare(p2 ComparableVersion) int {
+	if p1.Scheme.IsMinecraft() {
+		c, _ := compareMinecraft(p1, p2)
+		return c
+	}
+	if p1.Scheme == Maven {
+		return compareMaven(p1, p2)
+	}
+	return compareSemver(p1, p2)
+}
+
+// Eq checks whether p1 is equal to p2, i.e., they have the same precedence.
 func (p1 ComparableVersion) Eq(p2 ComparableVersion) bool {
 	if !p1.schemeMatch(p2) {
 		return false
 	}
-	return p1.Major == p2.Major && p1.Minor == p2.Minor && p1.Patch == p2.Patch
+	return p1.compare(p2) == 0
 }
 
 // StrictEq checks whether p1 is strictly equal to p2. This includes
@@ -70,14 +108,14 @@ func (p1 ComparableVersion) StrictEq(p2 ComparableVersion) bool {
 	return p1.Eq(p2)
 }
 
+// WeakEq is for the '~' operator. It checks whether p1 is at least p2, with
+// the same major and minor version, e.g., ~1.2.3 is met by 1.2.9 but not by
+// 1.3.0-beta.
 func (p1 ComparableVersion) WeakEq(p2 ComparableVersion) bool {
-	if p1.Minor == 0 && p1.Patch == 0 {
-		// if a minor is not specified, only compare major
-		return p1.Major == p2.Major
-	} else {
-		// if a minor is specified, only ignore patch
-		return p1.Major == p2.Major && p1.Minor == p2.Minor
+	if !p1.schemeMatch(p2) {
+		return false
 	}
+	return p1.Gte(p2) && p1.Major == p2.Major && p1.Minor == p2.Minor
 }
 
 // Neq checks whether p1 is not equal to p2.
@@ -93,37 +131,7 @@ func (p1 ComparableVersion) Lt(p2 ComparableVersion) bool {
 	if !p1.schemeMatch(p2) {
 		return false
 	}
-
-	if p1.Major < p2.Major {
-		return true
-	} else if p1.Major > p2.Major {
-		return false
-	}
-	if p1.Minor < p2.Minor {
-		return true
-	} else if p1.Minor > p2.Minor {
-		return false
-	}
-	if p1.Patch < p2.Patch {
-		return true
-	}
-
-	// Usually a version with a prerelease tag is considered lower than
-	// the same version without a prerelease tag.
-	if p1.Prerelease == "" {
-		if p2.Prerelease != "" {
-			return true
-		}
-		return false
-	} else {
-		if p2.Prerelease != "" {
-			// lexicographical order
-			return strings.Compare(p1.Prerelease, p2.Prerelease) < 0
-		} else {
-			// p1 has a prerelease tag, p2 does not.
-			return false
-		}
-	}
+	return p1.compare(p2) < 0
 }
 
 // Gt checks whether p1 is greater than p2.
@@ -131,51 +139,16 @@ func (p1 ComparableVersion) Gt(p2 ComparableVersion) bool {
 	if !p1.schemeMatch(p2) {
 		return false
 	}
-
-	if p1.Major > p2.Major {
-		return true
-	} else if p1.Major < p2.Major {
-		return false
-	}
-	if p1.Minor > p2.Minor {
-		return true
-	} else if p1.Minor < p2.Minor {
-		return false
-	}
-	if p1.Patch > p2.Patch {
-		return true
-	}
-
-	// Usually a version with a prerelease tag is considered lower than
-	// the same version without a prerelease tag.
-	if p1.Prerelease == "" {
-		if p2.Prerelease != "" {
-			return false
-		}
-		return false
-	} else {
-		if p2.Prerelease != "" {
-			// lexicographical order
-			return strings.Compare(p1.Prerelease, p2.Prerelease) > 0
-		} else {
-			// p1 has a prerelease tag, p2 does not.
-			return true
-		}
-	}
+	return p1.compare(p2) > 0
 }
 
-// WeakGt is for being compatible with the '^' operator in semver. Like Gt,
-// it checks whether p1 is greater than p2. However, it does not allow
-// the major version to be different.
+// WeakGt is for being compatible with the '^' operator in semver. It checks
+// whether p1 is at least p2, with the same major version.
 func (p1 ComparableVersion) WeakGt(p2 ComparableVersion) bool {
 	if !p1.schemeMatch(p2) {
 		return false
 	}
-
-	if p1.Major != p2.Major {
-		return false
-	}
-	return p1.Gt(p2)
+	return p1.Gte(p2) && p1.Major == p2.Major
 }
 
 // Lte checks whether p1 is less than or equal to p2.
@@ -183,8 +156,7 @@ func (p1 ComparableVersion) Lte(p2 ComparableVersion) bool {
 	if !p1.schemeMatch(p2) {
 		return false
 	}
-
-	return p1.Lt(p2) || p1.Eq(p2)
+	return p1.compare(p2) <= 0
 }
 
 // Gte checks whether p1 is greater than or equal to p2.
@@ -192,8 +164,7 @@ func (p1 ComparableVersion) Gte(p2 ComparableVersion) bool {
 	if !p1.schemeMatch(p2) {
 		return false
 	}
-
-	return p1.Gt(p2) || p1.Eq(p2)
+	return p1.compare(p2) >= 0
 }
 
 // ComparableVersion is a structural representation of a version (in numbers and
@@ -203,10 +174,19 @@ func (p1 ComparableVersion) Gte(p2 ComparableVersion) bool {
 // resolved.
 //
 // For Minecraft Snapshots, Major is the year, Minor is the week of the year,
-// and Patch is the rune at the end of the version string (to ascii code).
+// and Patch is the rune at the end of the version string (to ascii code). The
+// suffix of April Fools snapshots, e.g., potato in 24w14potato, is kept in
+// Prerelease. For Minecraft releases, Prerelease is the pre-release or release
+// candidate, e.g., pre3 or rc1. For legacy versions, Prerelease is the phase,
+// e.g., b for b1.7.3, and Build is what follows the numbers.
 //
 // In principle, you cannot compare two versions with different schema. This
-// type of comparison always returns false.
+// type of comparison always returns false. Versions of the game are the
+// exception, they are compared by Order when both are known in the version
+// manifest, and otherwise as compareMinecraft describes.
+//
+// Semver versions may have any number of components, those after Patch are in
+// Extra. They are ordered by SemVer 2.0 precedence, see compareSemver.
 //
 // The StrictEq method is checks for Prerelease.
 //
@@ -216,11 +196,13 @@ func (p1 ComparableVersion) Gte(p2 ComparableVersion) bool {
 // release of each Minor, such as 1.19.
 type ComparableVersion struct {
 	Scheme     VersionScheme // The type of versioning scheme used.
-	Major      uint16
-	Minor      uint16
-	Patch      uint16
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Extra      []uint64 // components after Patch, e.g., 4 in 1.2.3.4
 	Prerelease string
 	Build      string
+	Order      uint32 // position in the release order of the game, 0 if unknown
 }
 
 type VersionScheme uint8
@@ -232,6 +214,10 @@ const (
 	// https://zh.minecraft.wiki/w/%E7%89%88%E6%9C%AC%E6%A0%BC%E5%BC%8F#%E5%BF%AB%E7%85%A7%EF%BC%88Snapshot%EF%BC%89
 	MinecraftSnapshot
 	MinecraftRelease
+	MinecraftLegacy // alpha, beta, and earlier, e.g., b1.7.3
+
+	// Maven is the scheme of Forge and NeoForge mods, see compareMaven
+	Maven
 
 	Invalid
 )
@@ -240,16 +226,44 @@ var InvalidVersion = ComparableVersion{
 	Scheme: Invalid,
 }
 
+// String formats the version in its scheme, e.g., 1.2.3-beta+build or 24w14a.
+func (v ComparableVersion) String() string {
+	switch v.Scheme {
+	case Invalid:
+		return "invalid"
+	case MinecraftSnapshot, MinecraftRelease, MinecraftLegacy:
+		return minecraftString(v)
+	case Maven:
+		return mavenString(v)
+	}
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	for _, n := range v.Extra {
+		s += fmt.Sprintf(".%d", n)
+	}
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
 func (v ComparableVersion) Validate() bool {
 	switch v.Scheme {
 	case Semver:
-		return v.Major != 0 || v.Minor != 0 || v.Patch != 0
+		return v.Major != 0 || v.Minor != 0 || v.Patch != 0 || slices.ContainsFunc(
+			v.Extra,
+			func(n uint64) bool { return n != 0 },
+		)
 	case MinecraftSnapshot:
 		return v.Major != 0 && // year
 			v.Minor > 0 && v.Minor <= maxWeek && // week (work cycle)
 			v.Patch >= minSnapshotIndex && v.Patch <= maxSnapshotIndex // in-week index (as ascii code)
 	case MinecraftRelease:
-		return v.Major != 0 && v.Minor != 0
+		return v.Major != 0
+	case MinecraftLegacy:
+		return legacyPhaseRank(v.Prerelease) >= 0
 	case Invalid:
 		return false
 	default:
@@ -259,9 +273,9 @@ func (v ComparableVersion) Validate() bool {
 }
 
 const (
-	maxWeek          uint16 = 52 + 2
-	maxSnapshotIndex        = uint16('h')
-	minSnapshotIndex        = uint16('a')
+	maxWeek          uint64 = 52 + 2
+	maxSnapshotIndex        = uint64('z') // April Fools snapshots go past h
+	minSnapshotIndex        = uint64('a')
 )
 
 // Dependency represents a dependency requirement for a package.
@@ -271,10 +285,16 @@ const (
 // Dependency.Constraint is a 2d-array. The outer array were evaluated with OR,
 // while the inner array were evaluated with AND. While it is nil or empty, it
 // means there is no constraint (all versions are acceptable).
+//
+// An Inverse dependency is an incompatibility, e.g., the breaks and conflicts
+// of Fabric. Its Constraint is the versions that must not be installed, and it
+// is met when the package is not installed at all. A Mandatory one breaks the
+// package, others are only worth a warning.
 type Dependency struct {
 	Id         PackageId
 	Constraint VersionConstraintExpression
 	Mandatory  bool
+	Inverse    bool
 }
 
 type VersionConstraintExpression [][]VersionConstraint
@@ -284,35 +304,28 @@ type VersionConstraint struct {
 	Operator VersionOperator
 }
 
-// Inverse inverts the version constraint expression.
-// This functions is in-place.
-func (exps VersionConstraintExpression) Inverse() VersionConstraintExpression {
-	tools.ForEachOnMatrix(
-		exps,
-		func(exp VersionConstraint) { exp.Inverse() },
-	)
-	return exps
+// String formats the expression with operator signs, e.g., ">=1.0.0 <2.0.0 ||
+// =3.0.0". An expression any version satisfies without a constraint is "*".
+func (exps VersionConstraintExpression) String() string {
+	if exps.isAny() {
+		return "*"
+	}
+	var or []string
+	for _, and := range exps {
+		var terms []string
+		for _, c := range and {
+			terms = append(terms, c.String())
+		}
+		or = append(or, strings.Join(terms, " "))
+	}
+	return strings.Join(or, " || ")
 }
 
-// Inverse inverts the version constraint.
-// This function is in-place.
-func (exp *VersionConstraint) Inverse() {
-	switch exp.Operator {
-	case OpEq:
-		exp.Operator = OpNeq
-	case OpNeq:
-		exp.Operator = OpEq
-	case OpGt:
-		exp.Operator = OpLte
-	case OpWeakGt:
-		exp.Operator = OpLte
-	case OpGte:
-		exp.Operator = OpLt
-	case OpLt:
-		exp.Operator = OpGte
-	case OpLte:
-		exp.Operator = OpGt
+func (c VersionConstraint) String() string {
+	if c.Operator == OpNone {
+		return "none"
 	}
+	return c.Operator.ToSign() + c.Value.String()
 }
 
 func (d Dependency) Satisfy(
@@ -323,11 +336,16 @@ func (d Dependency) Satisfy(
 		return false
 	}
 
-	if d.Constraint == nil || tools.IsEmptyVector(d.Constraint) {
+	return d.Constraint.Contains(v)
+}
+
+// Contains tells whether the version satisfies the expression.
+func (exps VersionConstraintExpression) Contains(v ComparableVersion) bool {
+	if exps.isAny() {
 		return true
 	}
 
-	for _, orStatements := range d.Constraint {
+	for _, orStatements := range exps {
 		satisfied := true
 		for _, andStatements := range orStatements {
 			if !andStatements.Operator.Comparator()(v, andStatements.Value) {
@@ -355,6 +373,7 @@ var operatorFunctions = map[VersionOperator]versionComparator{
 	OpGte:    func(p1, p2 ComparableVersion) bool { return p1.Gte(p2) },
 	OpLt:     func(p1, p2 ComparableVersion) bool { return p1.Lt(p2) },
 	OpLte:    func(p1, p2 ComparableVersion) bool { return p1.Lte(p2) },
+	OpNone:   func(p1, p2 ComparableVersion) bool { return false },
 }
 
 const (
@@ -366,6 +385,7 @@ const (
 	OpGte
 	OpLt
 	OpLte
+	OpNone // no version at all, e.g., the negation of any version
 )
 
 func (op VersionOperator) String() string {
@@ -386,6 +406,8 @@ func (op VersionOperator) String() string {
 		return "less than"
 	case OpLte:
 		return "less than or equal"
+	case OpNone:
+		return "none"
 	default:
 		return "unknown"
 	}
@@ -414,27 +436,6 @@ func (op VersionOperator) ToSign() string {
 	}
 }
 
-func (op VersionOperator) Inverse() VersionOperator {
-	switch op {
-	case OpEq:
-		return OpNeq
-	case OpNeq:
-		return OpEq
-	case OpGt:
-		return OpLte
-	case OpWeakGt:
-		return OpLte
-	case OpGte:
-		return OpLt
-	case OpLt:
-		return OpGte
-	case OpLte:
-		return OpGt
-	default:
-		return op
-	}
-}
-
 func (op VersionOperator) Comparator() versionComparator {
 	return operatorFunctions[op]
 }
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type UrlType uint8
 
 const (
@@ -101,9 +103,20 @@ type (
 		FileUrl  string
 		Filename string
 
-		// Not implementing this for now until I found a good library to handle it.
-		// Hash       string
-		// HashMethod string
+		// Hash is the hex digest of the file, empty if the source does not
+		// publish one.
+		Hash       string
+		HashMethod HashMethod
+	}
+
+	// ProjectVersion is a single published version of a project, as listed
+	// by a remote source. It is used to pick a version before installation.
+	ProjectVersion struct {
+		Version      RawVersion
+		GameVersions []RawVersion
+		Platforms    []Platform
+		Prerelease   bool
+		Published    time.Time
 	}
 
 	// PlatformSupport reflects the support information of the whole project. For
@@ -114,3 +127,12 @@ type (
 		Authentic         bool
 	}
 )
+
+// HashMethod names a digest algorithm published by sources.
+type HashMethod string
+
+const (
+	HashSha1   HashMethod = "sha1"
+	HashSha256 HashMethod = "sha256"
+	HashSha512 HashMethod = "sha512"
+)
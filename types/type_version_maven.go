@@ -0,0 +1,226 @@
+package types
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"lucy/tools"
+)
+
+// Maven versions are ordered as Maven's ComparableVersion does, see
+// https://maven.apache.org/pom.html#version-order-specification
+//
+// A version is a list of items, split at '.' and '-', and where digits and
+// letters meet. A '-' or such a transition starts a sublist. Trailing zeros and
+// release qualifiers are dropped, so that 1.0 equals 1 and 1.0-final.
+
+// mavenString formats the version as its leading numbers followed by the rest
+// as it was written, which is kept in Prerelease, e.g., 1.0.0-beta-2.
+func mavenString(v ComparableVersion) string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	for _, n := range v.Extra {
+		s += fmt.Sprintf(".%d", n)
+	}
+	return s + v.Prerelease
+}
+
+func compareMaven(p1, p2 ComparableVersion) int {
+	return parseMavenItems(mavenString(p1)).compare(parseMavenItems(mavenString(p2)))
+}
+
+// mavenItem is a number, a qualifier, or a sublist. A nil mavenItem stands
+// for a missing one, e.g., the fourth number of 1.0.0 compared with 1.0.0.1.
+type mavenItem interface {
+	compare(other mavenItem) int
+	isNull() bool
+}
+
+type (
+	mavenInt       string // digits, without leading zeros
+	mavenQualifier string // lowercase
+	mavenList      []mavenItem
+)
+
+// mavenQualifiers are the known qualifiers, in order. The empty one is the
+// release. Unknown qualifiers come after all of them, in lexical order.
+var mavenQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+var mavenAliases = map[string]string{"ga": "", "final": "", "release": "", "cr": "rc"}
+
+func mavenQualifierRank(q string) string {
+	for i, known := range mavenQualifiers {
+		if q == known {
+			return fmt.Sprint(i)
+		}
+	}
+	return fmt.Sprintf("%d-%s", len(mavenQualifiers), q)
+}
+
+var mavenReleaseRank = mavenQualifierRank("")
+
+func (i mavenInt) isNull() bool {
+	return i == ""
+}
+
+func (i mavenInt) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		return tools.Ternary(i.isNull(), 0, 1)
+	case mavenInt:
+		return cmp.Or(cmp.Compare(len(i), len(o)), strings.Compare(string(i), string(o)))
+	default:
+		// 1.1 > 1-sp and 1.1 > 1-1
+		return 1
+	}
+}
+
+func (s mavenQualifier) isNull() bool {
+	return mavenQualifierRank(string(s)) == mavenReleaseRank
+}
+
+func (s mavenQualifier) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		return strings.Compare(mavenQualifierRank(string(s)), mavenReleaseRank)
+	case mavenQualifier:
+		return strings.Compare(mavenQualifierRank(string(s)), mavenQualifierRank(string(o)))
+	case mavenInt:
+		return -1
+	default:
+		// 1-beta < 1-1
+		return -1
+	}
+}
+
+func (l mavenList) isNull() bool {
+	return len(l) == 0
+}
+
+func (l mavenList) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		// The first item that is not null decides, e.g., 1-0.1 > 1
+		for _, item := range l {
+			if c := item.compare(nil); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case mavenInt:
+		return -1
+	case mavenQualifier:
+		return 1
+	case mavenList:
+		for i := range max(len(l), len(o)) {
+			var left, right mavenItem
+			if i < len(l) {
+				left = l[i]
+			}
+			if i < len(o) {
+				right = o[i]
+			}
+			var c int
+			if left == nil {
+				c = -right.compare(nil)
+			} else {
+				c = left.compare(right)
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	return 0
+}
+
+// normalize drops the trailing null items of the list, and the null items
+// before a trailing sublist.
+func (l mavenList) normalize() mavenList {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].isNull() {
+			l = append(l[:i], l[i+1:]...)
+		} else if _, ok := l[i].(mavenList); !ok {
+			break
+		}
+	}
+	return l
+}
+
+func parseMavenItems(version string) mavenList {
+	version = strings.ToLower(version)
+	// Sublists are built innermost last, so they are kept by pointer until
+	// the version is read
+	root := &mavenList{}
+	stack := []*mavenList{root}
+	list := root
+	push := func() {
+		sub := &mavenList{}
+		stack = append(stack, sub)
+		list = sub
+	}
+	item := func(digits bool, s string, followedByDigit bool) mavenItem {
+		if digits {
+			return mavenInt(strings.TrimLeft(s, "0"))
+		}
+		if followedByDigit && len(s) == 1 {
+			switch s {
+			case "a":
+				s = "alpha"
+			case "b":
+				s = "beta"
+			case "m":
+				s = "milestone"
+			}
+		}
+		if alias, ok := mavenAliases[s]; ok {
+			s = alias
+		}
+		return mavenQualifier(s)
+	}
+	isDigit := false
+	start := 0
+	for i, c := range version {
+		switch {
+		case c == '.':
+			if i == start {
+				*list = append(*list, mavenInt(""))
+			} else {
+				*list = append(*list, item(isDigit, version[start:i], false))
+			}
+			start = i + 1
+		case c == '-':
+			if i == start {
+				*list = append(*list, mavenInt(""))
+			} else {
+				*list = append(*list, item(isDigit, version[start:i], false))
+			}
+			start = i + 1
+			push()
+		case c >= '0' && c <= '9':
+			if !isDigit && i > start {
+				*list = append(*list, item(false, version[start:i], true))
+				start = i
+				push()
+			}
+			isDigit = true
+		default:
+			if isDigit && i > start {
+				*list = append(*list, item(true, version[start:i], false))
+				start = i
+				push()
+			}
+			isDigit = false
+		}
+	}
+	if len(version) > start {
+		*list = append(*list, item(isDigit, version[start:], false))
+	}
+
+	// Nest the sublists, each into the one before it
+	for i := len(stack) - 1; i > 0; i-- {
+		*stack[i-1] = append(*stack[i-1], stack[i].normalize())
+	}
+	return root.normalize()
+}
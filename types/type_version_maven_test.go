@@ -0,0 +1,86 @@
+package types
+
+import "testing"
+
+// The orderings below are those of Maven's ComparableVersionTest.
+
+// Each version is older than the next.
+var (
+	mavenQualifierOrder = []string{
+		"1-alpha2snapshot", "1-alpha2", "1-alpha-123", "1-beta-2", "1-beta123", "1-m2", "1-m11",
+		"1-rc", "1-cr2", "1-rc123", "1-SNAPSHOT", "1", "1-sp", "1-sp2", "1-sp123", "1-abc",
+		"1-def", "1-pom-1", "1-1-snapshot", "1-1", "1-2", "1-123",
+	}
+	mavenNumberOrder = []string{
+		"2.0", "2-1", "2.0.a", "2.0.0.a", "2.0.2", "2.0.123", "2.1.0", "2.1-a", "2.1b",
+		"2.1-c", "2.1-1", "2.1.0.1", "2.2", "2.123", "11.a2", "11.a11", "11.b2", "11.b11",
+		"11.m2", "11.m11", "11", "11.a", "11b", "11c", "11m",
+	}
+)
+
+// Each group is of equal versions.
+var mavenEqual = [][]string{
+	{"1", "1.0", "1.0.0", "1-0", "1.0-0", "1-ga", "1-final", "1-release", "1.0-GA"},
+	{"1a", "1-a", "1.0-a", "1.0.0-a"},
+	{"1x", "1-x", "1.0-x", "1.0.0-x"},
+	{"1cr", "1rc"},
+	{"1a1", "1-alpha-1"},
+	{"1b2", "1-beta-2"},
+	{"1m3", "1-milestone-3"},
+	{"1X", "1x"},
+	{"1A", "1a"},
+	{"1-SNAPSHOT", "1-snapshot"},
+}
+
+func TestCompareMavenOrder(t *testing.T) {
+	for _, order := range [][]string{mavenQualifierOrder, mavenNumberOrder} {
+		for i, a := range order {
+			for j, b := range order {
+				want := 0
+				switch {
+				case i < j:
+					want = -1
+				case i > j:
+					want = 1
+				}
+				if c := parseMavenItems(a).compare(parseMavenItems(b)); c != want {
+					t.Errorf("compare(%s, %s) = %d, want %d", a, b, c, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCompareMavenEqual(t *testing.T) {
+	for _, group := range mavenEqual {
+		for _, a := range group {
+			for _, b := range group {
+				if c := parseMavenItems(a).compare(parseMavenItems(b)); c != 0 {
+					t.Errorf("compare(%s, %s) = %d, want 0", a, b, c)
+				}
+			}
+		}
+	}
+}
+
+// A sublist is only null when all its items are, see MNG-5568.
+func TestCompareMavenSublist(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1-0.1", "1", 1},
+		{"1-0-alpha", "1", -1},
+		{"1-0.0", "1", 0},
+		{"1.0-alpha1", "1.0-alpha2", -1},
+		{"1", "1.1", -1},
+	}
+	for _, tt := range tests {
+		if c := parseMavenItems(tt.a).compare(parseMavenItems(tt.b)); c != tt.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", tt.a, tt.b, c, tt.want)
+		}
+		if c := parseMavenItems(tt.b).compare(parseMavenItems(tt.a)); c != -tt.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", tt.b, tt.a, c, -tt.want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package types
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"lucy/tools"
+)
+
+func (s VersionScheme) IsMinecraft() bool {
+	return s == MinecraftRelease || s == MinecraftSnapshot || s == MinecraftLegacy
+}
+
+// Phases of the game before 1.0, oldest first. They are kept in the Prerelease
+// of a MinecraftLegacy version.
+var legacyPhases = []string{"rd", "c", "in", "inf", "a", "b"}
+
+func legacyPhaseRank(phase string) int {
+	for i, p := range legacyPhases {
+		if p == phase {
+			return i
+		}
+	}
+	return -1
+}
+
+// compareMinecraft orders two versions of the game. When both have an Order,
+// that is, they are listed in the version manifest, it decides. Otherwise,
+// e.g., for a bound built with Next, versions of the same scheme are compared
+// by their shape, legacy versions are older than any other, and a snapshot and
+// a release are placed by minecraftCycles. The cycles are read from the
+// manifest as well when it is, so that both ways agree.
+func compareMinecraft(p1, p2 ComparableVersion) (c int, ok bool) {
+	if p1.Order != 0 && p2.Order != 0 {
+		return cmp.Compare(p1.Order, p2.Order), true
+	}
+	if p1.Scheme != p2.Scheme {
+		switch {
+		case p1.Scheme == MinecraftLegacy:
+			return -1, true
+		case p2.Scheme == MinecraftLegacy:
+			return 1, true
+		case p1.Scheme == MinecraftSnapshot:
+			return compareSnapshotToRelease(p1, p2)
+		default:
+			c, ok = compareSnapshotToRelease(p2, p1)
+			return -c, ok
+		}
+	}
+
+	if p1.Scheme == MinecraftLegacy {
+		c = cmp.Compare(legacyPhaseRank(p1.Prerelease), legacyPhaseRank(p2.Prerelease))
+		if c != 0 {
+			return c, true
+		}
+	}
+	c = cmp.Or(
+		cmp.Compare(p1.Major, p2.Major),
+		cmp.Compare(p1.Minor, p2.Minor),
+		cmp.Compare(p1.Patch, p2.Patch),
+	)
+	if c != 0 {
+		return c, true
+	}
+	switch p1.Scheme {
+	case MinecraftRelease:
+		return compareMinecraftPrerelease(p1.Prerelease, p2.Prerelease), true
+	case MinecraftSnapshot:
+		return strings.Compare(p1.Prerelease, p2.Prerelease), true
+	default:
+		return strings.Compare(p1.Build, p2.Build), true
+	}
+}
+
+// MinecraftCycle is a release listed with the last snapshot published before
+// its first pre-release, see compareSnapshotToRelease.
+type MinecraftCycle struct {
+	Release  [3]uint64 // major, minor, patch
+	Snapshot [3]uint64 // year, week, letter
+}
+
+// minecraftCycles orders snapshots and releases, oldest first. Releases
+// without snapshots of their own are left out, they come right after the
+// listed release they follow. It starts as builtinCycles, and is replaced by
+// the cycles of the version manifest once that is read, see
+// SetMinecraftCycles.
+var minecraftCycles atomic.Pointer[[]MinecraftCycle]
+
+func init() {
+	minecraftCycles.Store(&builtinCycles)
+}
+
+// SetMinecraftCycles replaces the ordering of snapshots and releases, e.g.,
+// with one read from the version manifest. Versions compared by Order then
+// agree with those compared by their cycles, as a range built with Next has
+// no Order to compare by.
+func SetMinecraftCycles(cycles []MinecraftCycle) {
+	if len(cycles) != 0 {
+		minecraftCycles.Store(&cycles)
+	}
+}
+
+// builtinCycles is the fallback ordering, for when the version manifest is not
+// available.
+var builtinCycles = []MinecraftCycle{
+	{[3]uint64{1, 3, 1}, [3]uint64{12, 30, 'e'}},
+	{[3]uint64{1, 4, 2}, [3]uint64{12, 42, 'b'}},
+	{[3]uint64{1, 4, 6}, [3]uint64{12, 50, 'b'}},
+	{[3]uint64{1, 5, 0}, [3]uint64{13, 10, 'b'}},
+	{[3]uint64{1, 6, 1}, [3]uint64{13, 26, 'a'}},
+	{[3]uint64{1, 7, 2}, [3]uint64{13, 43, 'a'}},
+	{[3]uint64{1, 7, 4}, [3]uint64{13, 49, 'a'}},
+	{[3]uint64{1, 8, 0}, [3]uint64{14, 34, 'd'}},
+	{[3]uint64{1, 9, 0}, [3]uint64{15, 51, 'b'}},
+	{[3]uint64{1, 10, 0}, [3]uint64{16, 21, 'b'}},
+	{[3]uint64{1, 11, 0}, [3]uint64{16, 44, 'a'}},
+	{[3]uint64{1, 11, 1}, [3]uint64{16, 50, 'a'}},
+	{[3]uint64{1, 12, 0}, [3]uint64{17, 18, 'b'}},
+	{[3]uint64{1, 12, 1}, [3]uint64{17, 31, 'a'}},
+	{[3]uint64{1, 13, 0}, [3]uint64{18, 22, 'c'}},
+	{[3]uint64{1, 13, 1}, [3]uint64{18, 33, 'a'}},
+	{[3]uint64{1, 14, 0}, [3]uint64{19, 14, 'b'}},
+	{[3]uint64{1, 15, 0}, [3]uint64{19, 46, 'b'}},
+	{[3]uint64{1, 16, 0}, [3]uint64{20, 22, 'a'}},
+	{[3]uint64{1, 16, 2}, [3]uint64{20, 30, 'a'}},
+	{[3]uint64{1, 16, 5}, [3]uint64{20, 51, 'a'}},
+	{[3]uint64{1, 17, 0}, [3]uint64{21, 20, 'a'}},
+	{[3]uint64{1, 18, 0}, [3]uint64{21, 44, 'a'}},
+	{[3]uint64{1, 18, 2}, [3]uint64{22, 7, 'a'}},
+	{[3]uint64{1, 19, 0}, [3]uint64{22, 19, 'a'}},
+	{[3]uint64{1, 19, 1}, [3]uint64{22, 24, 'a'}},
+	{[3]uint64{1, 19, 3}, [3]uint64{22, 46, 'a'}},
+	{[3]uint64{1, 19, 4}, [3]uint64{23, 7, 'a'}},
+	{[3]uint64{1, 20, 0}, [3]uint64{23, 18, 'a'}},
+	{[3]uint64{1, 20, 2}, [3]uint64{23, 35, 'a'}},
+	{[3]uint64{1, 20, 3}, [3]uint64{23, 46, 'a'}},
+	{[3]uint64{1, 20, 5}, [3]uint64{24, 14, 'a'}},
+	{[3]uint64{1, 21, 0}, [3]uint64{24, 21, 'b'}},
+	{[3]uint64{1, 21, 2}, [3]uint64{24, 40, 'a'}},
+	{[3]uint64{1, 21, 4}, [3]uint64{24, 46, 'a'}},
+	{[3]uint64{1, 21, 5}, [3]uint64{25, 10, 'a'}},
+	{[3]uint64{1, 21, 6}, [3]uint64{25, 21, 'a'}},
+	{[3]uint64{1, 21, 9}, [3]uint64{25, 37, 'a'}},
+}
+
+// compareSnapshotToRelease orders a snapshot and a release by minecraftCycles.
+// A snapshot comes before the first listed release whose last snapshot is not
+// older than it, and before the releases that follow. A release newer than
+// every listed one cannot be compared with a snapshot newer than every listed
+// one.
+func compareSnapshotToRelease(snapshot, release ComparableVersion) (c int, ok bool) {
+	components := func(a, b [3]uint64) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]), cmp.Compare(a[2], b[2]))
+	}
+	s := [3]uint64{snapshot.Major, snapshot.Minor, snapshot.Patch}
+	r := [3]uint64{release.Major, release.Minor, release.Patch}
+	cycles := *minecraftCycles.Load()
+	sCycle := len(cycles)
+	for i, cycle := range cycles {
+		if components(s, cycle.Snapshot) <= 0 {
+			sCycle = i
+			break
+		}
+	}
+	// The cycle of the release is the last listed one it is not older than
+	rCycle := -1
+	for i, cycle := range cycles {
+		if components(r, cycle.Release) >= 0 {
+			rCycle = i
+		}
+	}
+	if sCycle == len(cycles) &&
+		rCycle == len(cycles)-1 &&
+		components(r, cycles[rCycle].Release) > 0 {
+		return 0, false
+	}
+	return tools.Ternary(sCycle <= rCycle, -1, 1), true
+}
+
+// compareMinecraftPrerelease orders pre-releases before release candidates,
+// and both before the release.
+func compareMinecraftPrerelease(s1, s2 string) int {
+	rank := func(s string) (int, int) {
+		if s == "" {
+			return 2, 0
+		}
+		for i, kind := range []string{"pre", "rc"} {
+			if n, ok := strings.CutPrefix(s, kind); ok {
+				number, _ := strconv.Atoi(n)
+				return i, number
+			}
+		}
+		return -1, 0
+	}
+	kind1, n1 := rank(s1)
+	kind2, n2 := rank(s2)
+	return cmp.Or(cmp.Compare(kind1, kind2), cmp.Compare(n1, n2))
+}
+
+// minecraftString formats the version the way Mojang names it, except that
+// old pre-releases are written like new ones, e.g., 1.14-pre2 for
+// "1.14 Pre-Release 2".
+func minecraftString(v ComparableVersion) string {
+	switch v.Scheme {
+	case MinecraftSnapshot:
+		suffix := v.Prerelease
+		if suffix == "" {
+			suffix = string(rune(v.Patch))
+		}
+		return fmt.Sprintf("%02dw%02d%s", v.Major, v.Minor, suffix)
+	case MinecraftLegacy:
+		switch v.Prerelease {
+		case "rd", "in", "inf":
+			return v.Prerelease + "-" + v.Build
+		}
+		s := fmt.Sprintf("%s%d.%d", v.Prerelease, v.Major, v.Minor)
+		if v.Patch != 0 {
+			s += fmt.Sprintf(".%d", v.Patch)
+		}
+		return s + v.Build
+	default:
+		s := fmt.Sprintf("%d.%d", v.Major, v.Minor)
+		if v.Patch != 0 {
+			s += fmt.Sprintf(".%d", v.Patch)
+		}
+		if v.Prerelease != "" {
+			s += "-" + v.Prerelease
+		}
+		return s
+	}
+}
@@ -0,0 +1,85 @@
+package types
+
+import "testing"
+
+func release(major, minor, patch uint64, pre string) ComparableVersion {
+	return ComparableVersion{Scheme: MinecraftRelease, Major: major, Minor: minor, Patch: patch, Prerelease: pre}
+}
+
+func snapshot(year, week uint64, letter rune) ComparableVersion {
+	return ComparableVersion{Scheme: MinecraftSnapshot, Major: year, Minor: week, Patch: uint64(letter)}
+}
+
+func legacy(phase string, build string) ComparableVersion {
+	return ComparableVersion{Scheme: MinecraftLegacy, Prerelease: phase, Build: build}
+}
+
+// Each version is older than the next.
+var minecraftOrder = []ComparableVersion{
+	legacy("rd", "132211"),
+	legacy("c", "0.0.11a"),
+	legacy("in", "20091223-2"),
+	legacy("inf", "20100618"),
+	legacy("a", "1.2.6"),
+	legacy("b", "1.7.3"),
+	release(1, 0, 0, ""),
+	release(1, 14, 0, "pre2"),
+	release(1, 16, 4, ""),
+	snapshot(20, 45, 'a'),
+	release(1, 16, 5, ""),
+	snapshot(21, 3, 'a'),
+	release(1, 20, 1, ""),
+	snapshot(23, 31, 'a'),
+	snapshot(24, 14, 'a'),
+	release(1, 20, 5, "pre1"),
+	release(1, 20, 5, "pre3"),
+	release(1, 20, 5, "rc1"),
+	release(1, 20, 5, ""),
+	snapshot(24, 18, 'a'),
+	snapshot(24, 18, 'b'),
+	release(1, 21, 0, "pre1"),
+	release(1, 21, 0, ""),
+}
+
+func TestCompareMinecraft(t *testing.T) {
+	for i, a := range minecraftOrder {
+		for j, b := range minecraftOrder {
+			c, ok := compareMinecraft(a, b)
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if !ok || c != want {
+				t.Errorf("compareMinecraft(%s, %s) = %d, %v, want %d", a, b, c, ok, want)
+			}
+		}
+	}
+}
+
+func TestCompareMinecraftBeyondCycles(t *testing.T) {
+	// Both are newer than every listed cycle
+	if _, ok := compareMinecraft(snapshot(25, 41, 'a'), release(1, 21, 10, "")); ok {
+		t.Error("a snapshot and a release newer than the cycles are compared")
+	}
+	// Only one is
+	if c, ok := compareMinecraft(snapshot(25, 41, 'a'), release(1, 21, 9, "")); !ok || c != 1 {
+		t.Errorf("25w41a is not newer than 1.21.9")
+	}
+}
+
+func TestCompareMinecraftOrder(t *testing.T) {
+	// The manifest decides over the shape
+	a, b := release(1, 20, 1, ""), release(1, 20, 2, "")
+	a.Order, b.Order = 2, 1
+	if c, _ := compareMinecraft(a, b); c != 1 {
+		t.Errorf("compareMinecraft by Order = %d, want 1", c)
+	}
+	// Unless one of them has none
+	b.Order = 0
+	if c, _ := compareMinecraft(a, b); c != -1 {
+		t.Errorf("compareMinecraft by shape = %d, want -1", c)
+	}
+}
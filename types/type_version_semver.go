@@ -0,0 +1,113 @@
+package types
+
+import (
+	"cmp"
+	"strings"
+)
+
+// compareSemver orders two versions by SemVer 2.0 precedence. Numeric
+// components are compared first, a missing one counting as 0, so that 1.2
+// equals 1.2.0. A version with prerelease identifiers precedes the one
+// without. Build metadata is ignored.
+func compareSemver(p1, p2 ComparableVersion) int {
+	c1, c2 := p1.components(), p2.components()
+	for i := range max(len(c1), len(c2)) {
+		if c := cmp.Compare(componentAt(c1, i), componentAt(c2, i)); c != 0 {
+			return c
+		}
+	}
+	return comparePrerelease(p1.Prerelease, p2.Prerelease)
+}
+
+func componentAt(components []uint64, i int) uint64 {
+	if i < len(components) {
+		return components[i]
+	}
+	return 0
+}
+
+// comparePrerelease compares dot-separated prerelease identifiers one by one.
+// Numeric identifiers are compared as numbers, and precede alphanumeric ones,
+// which are compared in ASCII order. A shorter list of identifiers precedes a
+// longer one it is a prefix of, e.g., alpha < alpha.1 < alpha.2 < alpha.10 <
+// beta < 1.0.0.
+func comparePrerelease(s1, s2 string) int {
+	switch {
+	case s1 == s2:
+		return 0
+	case s1 == "":
+		return 1
+	case s2 == "":
+		return -1
+	}
+	ids1, ids2 := strings.Split(s1, "."), strings.Split(s2, ".")
+	for i := range min(len(ids1), len(ids2)) {
+		if c := compareIdentifier(ids1[i], ids2[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(ids1), len(ids2))
+}
+
+func compareIdentifier(a, b string) int {
+	numeric := func(s string) bool {
+		return s != "" && strings.Trim(s, "0123456789") == ""
+	}
+	switch na, nb := numeric(a), numeric(b); {
+	case na && nb:
+		// Compared by length first, so that numbers of any size work
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+	case na:
+		return -1
+	case nb:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// components returns all numeric components, Major first.
+func (v ComparableVersion) components() []uint64 {
+	return append([]uint64{v.Major, v.Minor, v.Patch}, v.Extra...)
+}
+
+// LowestPrerelease is the Prerelease that puts a version below all others with
+// the same numbers, e.g., 0 for SemVer, as in 1.3.0-0, and alpha for Maven,
+// as in 1.3.0-alpha.
+func (s VersionScheme) LowestPrerelease() string {
+	if s == Maven {
+		return "-alpha"
+	}
+	return "0"
+}
+
+// Next returns the lowest version above all versions that start with the
+// first n components of v, e.g., 1.3.0-0 for 1.2.5 and n = 2. It is the
+// exclusive upper bound of ranges such as ~1.2.5 or 1.2.x, and excludes the
+// prereleases of the next version as well. There is no such version for n < 1,
+// InvalidVersion is returned then.
+func (v ComparableVersion) Next(n int) ComparableVersion {
+	if n < 1 {
+		return InvalidVersion
+	}
+	components := v.components()
+	for len(components) < n {
+		components = append(components, 0)
+	}
+	components = components[:max(n, 3)]
+	for i := n; i < len(components); i++ {
+		components[i] = 0
+	}
+	components[n-1]++
+	next := ComparableVersion{
+		Scheme:     v.Scheme,
+		Major:      components[0],
+		Minor:      components[1],
+		Patch:      components[2],
+		Prerelease: v.Scheme.LowestPrerelease(),
+	}
+	if len(components) > 3 {
+		next.Extra = components[3:]
+	}
+	return next
+}
@@ -0,0 +1,118 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func semver(pre string, components ...uint64) ComparableVersion {
+	v := ComparableVersion{Scheme: Semver, Prerelease: pre}
+	for len(components) < 3 {
+		components = append(components, 0)
+	}
+	v.Major, v.Minor, v.Patch = components[0], components[1], components[2]
+	v.Extra = components[3:]
+	return v
+}
+
+func TestCompareSemver(t *testing.T) {
+	// The precedence example of SemVer 2.0, each older than the next
+	order := []ComparableVersion{
+		semver("alpha", 1),
+		semver("alpha.1", 1),
+		semver("alpha.beta", 1),
+		semver("beta", 1),
+		semver("beta.2", 1),
+		semver("beta.11", 1),
+		semver("rc.1", 1),
+		semver("", 1),
+		semver("", 1, 0, 1),
+		semver("", 1, 2),
+		semver("", 1, 2, 0, 1),
+		semver("", 1, 10),
+		semver("", 2),
+	}
+	for i, a := range order {
+		for j, b := range order {
+			want := 0
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if c := compareSemver(a, b); c != want {
+				t.Errorf("compareSemver(%s, %s) = %d, want %d", a, b, c, want)
+			}
+		}
+	}
+
+	equal := [][2]ComparableVersion{
+		{semver("", 1, 2), semver("", 1, 2, 0, 0)},
+		{semver("beta", 1), semver("beta", 1, 0, 0, 0)},
+		{
+			ComparableVersion{Scheme: Semver, Major: 1, Build: "1.20.1"},
+			ComparableVersion{Scheme: Semver, Major: 1, Build: "1.21"},
+		},
+	}
+	for _, pair := range equal {
+		if c := compareSemver(pair[0], pair[1]); c != 0 {
+			t.Errorf("compareSemver(%s, %s) = %d, want 0", pair[0], pair[1], c)
+		}
+	}
+}
+
+func TestComparePrerelease(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   int
+	}{
+		{"alpha", "alpha", 0},
+		{"", "alpha", 1},
+		{"alpha", "", -1},
+		{"1", "alpha", -1}, // numeric identifiers come first
+		{"alpha.2", "alpha.10", -1},
+		{"alpha.010", "alpha.10", 0},
+		{"alpha.99999999999999999999", "alpha.100000000000000000000", -1},
+		{"Beta", "alpha", -1}, // in ASCII order
+		{"0", "alpha", -1},
+	}
+	for _, tt := range tests {
+		if c := comparePrerelease(tt.s1, tt.s2); c != tt.want {
+			t.Errorf("comparePrerelease(%q, %q) = %d, want %d", tt.s1, tt.s2, c, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		v    ComparableVersion
+		n    int
+		want ComparableVersion
+	}{
+		{semver("", 1, 2, 5), 1, semver("0", 2)},
+		{semver("", 1, 2, 5), 2, semver("0", 1, 3)},
+		{semver("", 1, 2, 5), 3, semver("0", 1, 2, 6)},
+		{semver("beta", 1, 2, 5), 3, semver("0", 1, 2, 6)},
+		{semver("", 1, 2), 4, semver("0", 1, 2, 0, 1)},
+		{semver("", 1, 2, 3, 4, 5), 2, semver("0", 1, 3)},
+		{semver("", 1, 2, 3, 4, 5), 4, semver("0", 1, 2, 3, 5)},
+		{
+			ComparableVersion{Scheme: Maven, Major: 47, Minor: 2},
+			2,
+			ComparableVersion{Scheme: Maven, Major: 47, Minor: 3, Prerelease: "-alpha"},
+		},
+		{semver("", 1, 2, 5), 0, InvalidVersion},
+	}
+	for _, tt := range tests {
+		got := tt.v.Next(tt.n)
+		if got.Scheme != tt.want.Scheme ||
+			!slices.Equal(got.components(), tt.want.components()) ||
+			got.Prerelease != tt.want.Prerelease {
+			t.Errorf("%s.Next(%d) = %s, want %s", tt.v, tt.n, got, tt.want)
+		}
+		if tt.n > 0 && !tt.v.Lt(got) {
+			t.Errorf("%s.Next(%d) = %s is not above it", tt.v, tt.n, got)
+		}
+	}
+}
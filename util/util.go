@@ -2,20 +2,16 @@
 package util
 
 import (
-	"crypto/sha3"
+	"context"
+	"crypto/sha256"
 	"fmt"
-	"io"
 	"mime"
 	"net/http"
 	"net/url"
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
 	"time"
-
-	"lucy/cache"
-	"lucy/logger"
-	"lucy/tools"
 )
 
 const (
@@ -25,73 +21,57 @@ const (
 
 // DownloadFileWithCache downloads a file from the given URL and saves it to the specified directory.
 //
-// It calls cache.Network for cache retrieval and storage.
+// It calls cache.Network for cache retrieval and storage, see GetCached. The
+// expiration applies when the server does not tell how long the file stays
+// fresh, 0 for the default of the cache.
 func DownloadFileWithCache(
 	url string,
 	dir string,
 	expiration time.Duration,
 ) (file *os.File, hit bool, err error) {
-	if cache.Network.Exist(url) {
-		_, cache, err := cache.Network.Get(url)
-		if err != nil {
-			return nil, false, err
-		}
-		file, err = tools.CopyFile(
-			cache,
-			path.Join(dir, path.Base(cache.Name())),
-		)
-		if err != nil {
-			return nil, false, err
-		}
-		return file, true, err
-	}
-
-	file, data, err := DownloadFile(url, dir)
+	data, filename, _, hit, err := fetchCached(context.Background(), Client(), url, expiration)
 	if err != nil {
 		return nil, false, err
 	}
-	err = cache.Network.Add(data, file.Name(), url, expiration)
-	if err != nil {
-		logger.Warn(fmt.Errorf("failed to add file to cache: %w", err))
-	}
-	return file, false, nil
-}
-
-// DownloadFile downloads a file WITHOUT caching (either checking or storing).
-func DownloadFile(url string, dir string) (
-	file *os.File,
-	data []byte,
-	err error,
-) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, nil, err
-	}
-	data, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, nil, err
-	}
-	filename := speculateFilename(resp)
 	if filename == "" {
-		filename = fmt.Sprintf("%x", sha3.Sum256(data))
+		filename = fmt.Sprintf("%x", sha256.Sum256(data))
 	}
-	file, err = os.Create(path.Join(dir, filename))
+	file, err = os.Create(filepath.Join(dir, filename))
 	if err != nil {
-		return nil, nil, err
+		return nil, false, err
 	}
-	_, err = file.Write(data)
-	if err != nil {
-		return nil, nil, err
+	if _, err = file.Write(data); err != nil {
+		return nil, false, err
 	}
-	return file, data, nil
+	return file, hit, nil
 }
 
+// speculateFilename names the file of the response after its
+// Content-Disposition header, or else its URL. Either comes from the server,
+// so only the last element of the name is taken, see safeFilename.
 func speculateFilename(resp *http.Response) string {
 	if filename, ok := getFilenameFromHeader(resp); ok {
-		return filename
+		if filename = safeFilename(filename); filename != "" {
+			return filename
+		}
 	}
-	filename := getFilenameFromURL(resp.Request.URL.String())
-	return filename
+	return safeFilename(getFilenameFromURL(resp.Request.URL.String()))
+}
+
+// safeFilename returns the last element of the name, which stays inside the
+// directory it is joined to. Both separators are taken as such, whatever the
+// system is. It is empty if nothing is left, e.g., for ".." or "a/".
+func safeFilename(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if name == "" || strings.HasSuffix(name, "/") {
+		return ""
+	}
+	name = filepath.Base(name)
+	switch name {
+	case ".", "..", "/":
+		return ""
+	}
+	return name
 }
 
 func getFilenameFromHeader(resp *http.Response) (string, bool) {
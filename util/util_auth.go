@@ -0,0 +1,38 @@
+package util
+
+import (
+	"net/http"
+	"sync"
+)
+
+var (
+	authorizations   = make(map[string]string) // host to Authorization header
+	authorizationsMu sync.RWMutex
+)
+
+// SetAuthorization sends value as the Authorization header of HTTPS requests
+// to the host, and to no other host. Requests pointed to a mirror are matched
+// by the mirror, so credentials are never handed to a third party. An empty
+// value removes the authorization.
+func SetAuthorization(host string, value string) {
+	authorizationsMu.Lock()
+	defer authorizationsMu.Unlock()
+	if value == "" {
+		delete(authorizations, host)
+		return
+	}
+	authorizations[host] = value
+}
+
+// authorize adds the Authorization header of the host of the request, unless
+// the request already has one.
+func authorize(req *http.Request) {
+	if req.URL.Scheme != "https" || req.Header.Get("Authorization") != "" {
+		return
+	}
+	authorizationsMu.RLock()
+	defer authorizationsMu.RUnlock()
+	if value, ok := authorizations[req.URL.Host]; ok {
+		req.Header.Set("Authorization", value)
+	}
+}
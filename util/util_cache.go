@@ -0,0 +1,175 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"lucy/cache"
+	"lucy/logger"
+	"lucy/tools"
+)
+
+// GetCached is Get through the network cache. Fresh entries are served without
+// a request. Stale entries are revalidated with a conditional request, which
+// costs no transfer, and for GitHub no rate limit, when they did not change.
+//
+// Only 200 responses are cached. The status is that of the response, or 200
+// when served from the cache.
+func GetCached(url string) (data []byte, status int, err error) {
+	data, _, status, _, err = fetchCached(context.Background(), Client(), url, 0)
+	return data, status, err
+}
+
+// fetchCached implements GetCached with the client. The server decides how
+// long a response stays fresh; lifetime applies when it does not say, and 0
+// leaves it to the cache.
+func fetchCached(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	lifetime time.Duration,
+) (
+	data []byte,
+	filename string,
+	status int,
+	hit bool,
+	err error,
+) {
+	meta, fresh, ok := cache.Network.Lookup(url)
+	// Offline, a stale entry is all there is
+	if ok && (fresh || Offline()) {
+		if data, filename, err = readCached(url); err == nil {
+			logger.Debug("cache hit " + url)
+			return data, filename, http.StatusOK, true, nil
+		}
+		ok = false
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", 0, false, err
+	}
+	if ok {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+	resp, err := Do(client, req)
+	if err != nil {
+		// A stale response is better than none, e.g., when offline, but not
+		// when the caller gave up
+		if ok && ctx.Err() == nil {
+			if data, filename, staleErr := readCached(url); staleErr == nil {
+				logger.Warn(fmt.Errorf("using stale cache of %s: %w", url, err))
+				return data, filename, http.StatusOK, true, nil
+			}
+		}
+		return nil, "", 0, false, err
+	}
+	defer tools.CloseReader(resp.Body, logger.Warn)
+
+	expires, storable := expiresOf(resp.Header, lifetime)
+	if resp.StatusCode == http.StatusNotModified && ok {
+		if data, filename, err = readCached(url); err == nil {
+			logger.Debug("cache revalidated " + url)
+			if err := cache.Network.Renew(url, expires, metaOf(resp.Header)); err != nil {
+				logger.Warn(fmt.Errorf("failed to renew cache of %s: %w", url, err))
+			}
+			return data, filename, http.StatusOK, true, nil
+		}
+		// The entry is gone since the lookup, fetch it in full
+		return fetchUncached(ctx, client, url)
+	}
+
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", 0, false, err
+	}
+	filename = speculateFilename(resp)
+	if resp.StatusCode == http.StatusOK && storable {
+		err := cache.Network.AddWithMeta(data, filename, url, expires, metaOf(resp.Header))
+		if err != nil {
+			logger.Warn(fmt.Errorf("failed to add %s to cache: %w", url, err))
+		}
+	}
+	return data, filename, resp.StatusCode, false, nil
+}
+
+func fetchUncached(ctx context.Context, client *http.Client, url string) (
+	data []byte,
+	filename string,
+	status int,
+	hit bool,
+	err error,
+) {
+	resp, err := get(ctx, client, url)
+	if err != nil {
+		return nil, "", 0, false, err
+	}
+	defer tools.CloseReader(resp.Body, logger.Warn)
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", 0, false, err
+	}
+	return data, speculateFilename(resp), resp.StatusCode, false, nil
+}
+
+func readCached(url string) (data []byte, filename string, err error) {
+	hit, file, err := cache.Network.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	if !hit {
+		return nil, "", fmt.Errorf("%s is not cached", url)
+	}
+	defer tools.CloseReader(file, logger.Warn)
+	data, err = io.ReadAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, path.Base(file.Name()), nil
+}
+
+func metaOf(header http.Header) cache.Meta {
+	return cache.Meta{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
+
+// expiresOf reads the lifetime of a response from Cache-Control, then Expires.
+// Without either, lifetime applies, with zero meaning the default of the cache.
+// A response marked no-store must not be cached at all.
+func expiresOf(header http.Header, lifetime time.Duration) (expires time.Time, storable bool) {
+	now := time.Now()
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			return time.Time{}, false
+		case "no-cache":
+			// Cached, but revalidated on every use
+			return now, true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				return now.Add(time.Duration(seconds) * time.Second), true
+			}
+		}
+	}
+	if t, err := http.ParseTime(header.Get("Expires")); err == nil {
+		return t, true
+	}
+	if lifetime > 0 {
+		return now.Add(lifetime), true
+	}
+	return time.Time{}, true
+}
@@ -0,0 +1,607 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"lucy/logger"
+	"lucy/tools"
+	"lucy/types"
+)
+
+var (
+	ErrorHashMismatch = errors.New("hash mismatch")
+	ErrorHttpStatus   = errors.New("unexpected http status")
+)
+
+// DownloadTask is a single file for the Downloader.
+type DownloadTask struct {
+	Url string
+	Dir string
+
+	// Filename is speculated from the response when empty.
+	Filename string
+
+	// Hash is the expected hex digest. Verification is skipped when empty.
+	Hash       string
+	HashMethod types.HashMethod
+
+	// Mirrors host the same file as Url, see MirrorsOf. The first attempt
+	// races all of them, later attempts fail over in the order they
+	// responded. The urls of a task with mirrors are requested as they are,
+	// rather than pointed to the mirror of their host again.
+	Mirrors []string
+}
+
+// Name is the filename of the task when the response does not tell. A url
+// without a filename, e.g., one ending in a slash, is named after its hash.
+func (t DownloadTask) Name() string {
+	if name := safeFilename(t.Filename); name != "" {
+		return name
+	}
+	if name := safeFilename(getFilenameFromURL(t.Url)); name != "" {
+		return name
+	}
+	sum := sha256.Sum256([]byte(t.Url))
+	return hex.EncodeToString(sum[:8])
+}
+
+// partPath is where the task is streamed to before it is verified. It depends
+// on the task and its position in the batch rather than the url alone, so
+// that two tasks of the same url never share a part file, while a retry, or
+// the same batch run again, resumes it.
+func (t DownloadTask) partPath(id int) string {
+	sum := sha256.Sum256([]byte(t.Url + "\x00" + t.Filename + "\x00" + t.Hash))
+	return filepath.Join(t.Dir, "."+hex.EncodeToString(sum[:8])+"-"+strconv.Itoa(id)+".part")
+}
+
+type DownloadResult struct {
+	Task DownloadTask
+	Path string
+	Size int64
+	Err  error
+}
+
+// DownloadReporter receives the progress of the Downloader. Calls come from
+// the worker goroutines, implementations must be safe for concurrent use.
+type DownloadReporter interface {
+	// Start is called once the size of the task is known, total is -1 when
+	// the server does not tell.
+	Start(task int, name string, total int64)
+	Progress(task int, written int64)
+	// Verifying is called when the transfer completed and the hash is checked.
+	Verifying(task int)
+	// Retry is called before a failed attempt is retried.
+	Retry(task int, attempt int, err error)
+	Done(task int, err error)
+}
+
+// Downloader runs downloads in parallel. Failed attempts are retried with an
+// exponential backoff, and partially downloaded files are resumed with HTTP
+// Range requests when the server supports it.
+//
+// Every file is streamed to a hidden part file in its directory while being
+// hashed, and is only renamed to its final name after the hash is verified.
+type Downloader struct {
+	Workers  int
+	Retries  int
+	Backoff  time.Duration
+	Client   *http.Client
+	Reporter DownloadReporter
+}
+
+func NewDownloader() *Downloader {
+	return &Downloader{
+		Workers:  4,
+		Retries:  3,
+		Backoff:  500 * time.Millisecond,
+		Client:   Client(),
+		Reporter: nopReporter{},
+	}
+}
+
+// Download runs all tasks and blocks until they are finished. Results are in
+// the same order as tasks. A failed task does not stop the others.
+func (d *Downloader) Download(
+	ctx context.Context,
+	tasks []DownloadTask,
+) (results []DownloadResult) {
+	results = make([]DownloadResult, len(tasks))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for range max(d.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = d.download(ctx, i, tasks[i])
+			}
+		}()
+	}
+	for i := range tasks {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+func (d *Downloader) download(
+	ctx context.Context,
+	id int,
+	task DownloadTask,
+) (res DownloadResult) {
+	res.Task = task
+	defer func() { d.Reporter.Done(id, res.Err) }()
+
+	urls := append([]string{task.Url}, task.Mirrors...)
+	if len(task.Mirrors) != 0 {
+		ctx = withoutMirrors(ctx)
+	}
+	for attempt := 0; ; attempt++ {
+		fetch := d.getter(urls[attempt%len(urls)])
+		if attempt == 0 && len(urls) > 1 {
+			fetch = d.racer(urls)
+		}
+		res.Path, res.Size, res.Err = d.attempt(ctx, id, task, fetch)
+		if res.Err == nil {
+			break
+		}
+		if !retryable(res.Err) || attempt >= d.Retries {
+			return res
+		}
+		d.Reporter.Retry(id, attempt+1, res.Err)
+		logger.Info(fmt.Sprintf("retrying %s: %s", task.Url, res.Err))
+		select {
+		case <-ctx.Done():
+			res.Err = ctx.Err()
+			return res
+		case <-time.After(d.Backoff << attempt):
+		}
+	}
+	return res
+}
+
+// fetcher requests the file starting at offset. Responses other than 200 and
+// 206 are returned as statusError.
+type fetcher func(ctx context.Context, offset int64) (*http.Response, error)
+
+func (d *Downloader) getter(url string) fetcher {
+	return func(ctx context.Context, offset int64) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		}
+		resp, err := Do(d.Client, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			_ = resp.Body.Close()
+			return nil, statusError{resp.StatusCode}
+		}
+		return resp, nil
+	}
+}
+
+// raceBytes is how much a mirror must deliver to win a race. It is large
+// enough to tell a slow mirror apart, while small enough for the waste of
+// the losers to not matter.
+const raceBytes = 64 * 1024
+
+// racer requests all urls at once and keeps the first one that delivers
+// raceBytes, or the whole file if it is smaller. The losers are cancelled.
+//
+// urls is reordered in place to the order they responded, so that a retry
+// fails over to the next fastest one.
+func (d *Downloader) racer(urls []string) fetcher {
+	return func(ctx context.Context, offset int64) (*http.Response, error) {
+		type entrant struct {
+			index int
+			resp  *http.Response
+			err   error
+		}
+
+		ch := make(chan entrant, len(urls))
+		cancels := make([]context.CancelFunc, len(urls))
+		for i, url := range urls {
+			var raceCtx context.Context
+			raceCtx, cancels[i] = context.WithCancel(ctx)
+			go func() {
+				resp, err := d.getter(url)(raceCtx, offset)
+				if err == nil {
+					err = peek(resp, raceBytes)
+				}
+				ch <- entrant{index: i, resp: resp, err: err}
+			}()
+		}
+
+		var winner *http.Response
+		var errs []error
+		order := make([]string, 0, len(urls))
+		for range urls {
+			e := <-ch
+			order = append(order, urls[e.index])
+			switch {
+			case e.err != nil:
+				cancels[e.index]()
+				errs = append(errs, fmt.Errorf("%s: %w", urls[e.index], e.err))
+			case winner == nil:
+				winner = e.resp
+				winner.Body = &cancelOnClose{ReadCloser: winner.Body, cancel: cancels[e.index]}
+				logger.Debug("mirror race won by " + urls[e.index])
+				for i, cancel := range cancels {
+					if i != e.index {
+						cancel()
+					}
+				}
+			default:
+				_ = e.resp.Body.Close()
+				cancels[e.index]()
+			}
+		}
+		copy(urls, order)
+
+		if winner == nil {
+			return nil, errors.Join(errs...)
+		}
+		return winner, nil
+	}
+}
+
+// peek reads the first n bytes of the body, and puts them back in front of
+// the rest. The body is closed on error.
+func peek(resp *http.Response, n int) error {
+	head := make([]byte, n)
+	read, err := io.ReadFull(resp.Body, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		_ = resp.Body.Close()
+		return err
+	}
+	resp.Body = &prefixedBody{
+		Reader: io.MultiReader(bytes.NewReader(head[:read]), resp.Body),
+		Closer: resp.Body,
+	}
+	return nil
+}
+
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+func (d *Downloader) attempt(
+	ctx context.Context,
+	id int,
+	task DownloadTask,
+	fetch fetcher,
+) (dest string, size int64, err error) {
+	h, err := newHash(task.HashMethod)
+	if err != nil {
+		return "", 0, err
+	}
+
+	partPath := task.partPath(id)
+	part, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		_ = part.Close()
+		if err == nil {
+			return
+		}
+		// Keep the part file to resume from, unless it is corrupted or empty
+		if info, statErr := os.Stat(partPath); errors.Is(err, ErrorHashMismatch) ||
+			(statErr == nil && info.Size() == 0) {
+			_ = os.Remove(partPath)
+		}
+	}()
+
+	// Feed what we already have to the hash, so that it covers the whole file
+	// after the rest is appended.
+	offset, err := io.Copy(h, part)
+	if err != nil {
+		return "", 0, err
+	}
+
+	resp, err := fetch(ctx, offset)
+	if errors.Is(err, errRangeNotSatisfiable) {
+		// The part file is not shorter than the remote file, it is either
+		// complete or garbage. Drop it and let the retry download afresh.
+		_ = part.Truncate(0)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusPartialContent && offset > 0 &&
+		rangeStart(resp) != offset {
+		// The server resumed from elsewhere, appending would corrupt the file
+		logger.Debug("ignoring partial response of " + task.Url + " not starting at " + strconv.FormatInt(offset, 10))
+		_ = resp.Body.Close()
+		if _, err = restart(part, &h, task.HashMethod); err != nil {
+			return "", 0, err
+		}
+		offset = 0
+		if resp, err = fetch(ctx, 0); err != nil {
+			return "", 0, err
+		}
+		defer func() { _ = resp.Body.Close() }()
+	}
+	if resp.StatusCode == http.StatusPartialContent && offset > 0 {
+		logger.Debug("resuming " + task.Url + " from " + strconv.FormatInt(offset, 10))
+	} else {
+		// Range is not supported, or there is nothing to resume
+		if offset, err = restart(part, &h, task.HashMethod); err != nil {
+			return "", 0, err
+		}
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	name := task.Name()
+	if task.Filename == "" {
+		if filename := speculateFilename(resp); filename != "" {
+			name = filename
+		}
+	}
+	d.Reporter.Start(id, name, total)
+
+	w := &progressWriter{reporter: d.Reporter, task: id, written: offset}
+	d.Reporter.Progress(id, offset)
+	if _, err = part.Seek(0, io.SeekEnd); err != nil {
+		return "", 0, err
+	}
+	size, err = io.Copy(io.MultiWriter(part, h, w), resp.Body)
+	size += offset
+	if err != nil {
+		return "", size, err
+	}
+	if total >= 0 && size != total {
+		return "", size, io.ErrUnexpectedEOF
+	}
+
+	if task.Hash != "" {
+		d.Reporter.Verifying(id)
+		sum := hex.EncodeToString(h.Sum(nil))
+		if !strings.EqualFold(sum, task.Hash) {
+			return "", size, fmt.Errorf(
+				"%w: %s, expected %s, got %s",
+				ErrorHashMismatch, name, task.Hash, sum,
+			)
+		}
+	}
+
+	if err = part.Close(); err != nil {
+		return "", size, err
+	}
+	dest = filepath.Join(task.Dir, name)
+	if err = os.Rename(partPath, dest); err != nil {
+		return "", size, err
+	}
+	return dest, size, nil
+}
+
+// rangeStart returns the first byte of a 206 response, from its
+// Content-Range, e.g., "bytes 100-199/200". It is -1 if the header is missing
+// or malformed.
+func rangeStart(resp *http.Response) int64 {
+	unit, spec, ok := strings.Cut(resp.Header.Get("Content-Range"), " ")
+	if !ok || unit != "bytes" {
+		return -1
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
+
+// restart empties the part file and the hash.
+func restart(part *os.File, h *hash.Hash, method types.HashMethod) (int64, error) {
+	if err := part.Truncate(0); err != nil {
+		return 0, err
+	}
+	fresh, err := newHash(method)
+	if err != nil {
+		return 0, err
+	}
+	*h = fresh
+	return 0, nil
+}
+
+// VerifyFile checks the file against the hash.
+func VerifyFile(file string, sum string, method types.HashMethod) error {
+	h, err := newHash(method)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer tools.CloseReader(f, logger.Warn)
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), sum) {
+		return ErrorHashMismatch
+	}
+	return nil
+}
+
+func newHash(method types.HashMethod) (hash.Hash, error) {
+	switch method {
+	case types.HashSha1:
+		return sha1.New(), nil
+	case types.HashSha256:
+		return sha256.New(), nil
+	case types.HashSha512, "":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash method: %s", method)
+	}
+}
+
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string {
+	return ErrorHttpStatus.Error() + ": " + strconv.Itoa(e.code)
+}
+
+func (e statusError) Is(target error) bool {
+	return target == ErrorHttpStatus
+}
+
+var errRangeNotSatisfiable = statusError{http.StatusRequestedRangeNotSatisfiable}
+
+// retryable tells whether an attempt may succeed if tried again. Client errors
+// other than 408, 416 and 429 will not change by retrying.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// Limits that reset soon are already waited for by Do
+	if errors.Is(err, ErrorRateLimited) || errors.Is(err, ErrorOffline) {
+		return false
+	}
+	var se statusError
+	if errors.As(err, &se) {
+		switch {
+		case se.code == http.StatusRequestTimeout,
+			se.code == http.StatusRequestedRangeNotSatisfiable,
+			se.code == http.StatusTooManyRequests,
+			se.code >= 500:
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type progressWriter struct {
+	reporter DownloadReporter
+	task     int
+	written  int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	w.reporter.Progress(w.task, w.written)
+	return len(p), nil
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Start(int, string, int64) {}
+func (nopReporter) Progress(int, int64)      {}
+func (nopReporter) Verifying(int)            {}
+func (nopReporter) Retry(int, int, error)    {}
+func (nopReporter) Done(int, error)          {}
+
+// LineReporter prints a line when a download starts, passes every quarter,
+// is retried or finishes. It suits logs and terminals without live display.
+type LineReporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	names  map[int]string
+	totals map[int]int64
+	steps  map[int]int64
+}
+
+func NewLineReporter(w io.Writer) *LineReporter {
+	return &LineReporter{
+		w:      w,
+		names:  make(map[int]string),
+		totals: make(map[int]int64),
+		steps:  make(map[int]int64),
+	}
+}
+
+func (r *LineReporter) Start(task int, name string, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[task] = name
+	r.totals[task] = total
+	r.steps[task] = 0
+	_, _ = fmt.Fprintf(r.w, "downloading %s\n", name)
+}
+
+func (r *LineReporter) Progress(task int, written int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := r.totals[task]
+	if total <= 0 {
+		return
+	}
+	step := written * 4 / total
+	if step > r.steps[task] && step < 4 {
+		r.steps[task] = step
+		_, _ = fmt.Fprintf(r.w, "downloading %s %d%%\n", r.names[task], step*25)
+	}
+}
+
+func (r *LineReporter) Verifying(int) {}
+
+func (r *LineReporter) Retry(task int, attempt int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = fmt.Fprintf(r.w, "retrying %s (%d): %s\n", r.name(task), attempt, err)
+}
+
+func (r *LineReporter) Done(task int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		_, _ = fmt.Fprintf(r.w, "failed %s: %s\n", r.name(task), err)
+		return
+	}
+	_, _ = fmt.Fprintf(r.w, "downloaded %s\n", r.name(task))
+}
+
+// name falls back to the task number when the task failed before it started.
+func (r *LineReporter) name(task int) string {
+	if name, ok := r.names[task]; ok {
+		return name
+	}
+	return "#" + strconv.Itoa(task)
+}
@@ -0,0 +1,366 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"lucy/types"
+)
+
+var testContent = bytes.Repeat([]byte("lucy download test\n"), 4096)
+
+func testHash(data []byte) string {
+	sum := sha512.Sum512(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func testDownloader() *Downloader {
+	d := NewDownloader()
+	d.Backoff = time.Millisecond
+	d.Client = http.DefaultClient
+	return d
+}
+
+// serveContent answers with the content, honoring a Range header unless
+// ignoreRange is set.
+func serveContent(w http.ResponseWriter, r *http.Request, ignoreRange bool) {
+	start := 0
+	if spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes="); ok && !ignoreRange {
+		first, _, _ := strings.Cut(spec, "-")
+		start, _ = strconv.Atoi(first)
+		w.Header().Set(
+			"Content-Range",
+			fmt.Sprintf("bytes %d-%d/%d", start, len(testContent)-1, len(testContent)),
+		)
+		w.Header().Set("Content-Length", strconv.Itoa(len(testContent)-start))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.Header().Set("Content-Length", strconv.Itoa(len(testContent)))
+	}
+	_, _ = w.Write(testContent[start:])
+}
+
+// serveHalf sends the headers of the whole content but only half of it, and
+// then drops the connection.
+func serveHalf(w http.ResponseWriter) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(testContent)))
+	_, _ = w.Write(testContent[:len(testContent)/2])
+	w.(http.Flusher).Flush()
+	panic(http.ErrAbortHandler)
+}
+
+func checkResult(t *testing.T, res DownloadResult) {
+	t.Helper()
+	if res.Err != nil {
+		t.Fatalf("download failed: %v", res.Err)
+	}
+	data, err := os.ReadFile(res.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, testContent) {
+		t.Fatalf("got %d bytes, want the %d bytes of the content", len(data), len(testContent))
+	}
+	if res.Size != int64(len(testContent)) {
+		t.Errorf("size is %d, want %d", res.Size, len(testContent))
+	}
+	parts, _ := filepath.Glob(filepath.Join(filepath.Dir(res.Path), "*.part"))
+	if len(parts) != 0 {
+		t.Errorf("part files left behind: %v", parts)
+	}
+}
+
+func TestDownloaderRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		retries  int
+		wantErr  error
+	}{
+		{name: "no failure", failures: 0, retries: 3},
+		{name: "recovers", failures: 2, retries: 3},
+		{name: "gives up", failures: 3, retries: 2, wantErr: ErrorHttpStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(requests.Add(1)) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				serveContent(w, r, false)
+			}))
+			defer server.Close()
+
+			d := testDownloader()
+			d.Retries = tt.retries
+			res := d.Download(context.Background(), []DownloadTask{
+				{Url: server.URL + "/mod.jar", Dir: t.TempDir(), Hash: testHash(testContent)},
+			})[0]
+			if tt.wantErr != nil {
+				if !errors.Is(res.Err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", res.Err, tt.wantErr)
+				}
+				if got := int(requests.Load()); got != tt.retries+1 {
+					t.Errorf("got %d requests, want %d", got, tt.retries+1)
+				}
+				return
+			}
+			checkResult(t, res)
+			if got := int(requests.Load()); got != tt.failures+1 {
+				t.Errorf("got %d requests, want %d", got, tt.failures+1)
+			}
+		})
+	}
+}
+
+func TestDownloaderNoRetryOnClientError(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	res := testDownloader().Download(context.Background(), []DownloadTask{
+		{Url: server.URL + "/mod.jar", Dir: t.TempDir()},
+	})[0]
+	if !errors.Is(res.Err, ErrorHttpStatus) {
+		t.Fatalf("got error %v, want %v", res.Err, ErrorHttpStatus)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+}
+
+func TestDownloaderResume(t *testing.T) {
+	tests := []struct {
+		name string
+		// second answers the request after the first one was cut off
+		second    func(w http.ResponseWriter, r *http.Request)
+		wantRange []string // Range headers of the requests after the first
+	}{
+		{
+			name:      "206 from the offset",
+			second:    func(w http.ResponseWriter, r *http.Request) { serveContent(w, r, false) },
+			wantRange: []string{"bytes=" + strconv.Itoa(len(testContent)/2) + "-"},
+		},
+		{
+			name:      "range ignored",
+			second:    func(w http.ResponseWriter, r *http.Request) { serveContent(w, r, true) },
+			wantRange: []string{"bytes=" + strconv.Itoa(len(testContent)/2) + "-"},
+		},
+		{
+			// The server claims to resume, but from somewhere else
+			name: "206 from another offset",
+			second: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") == "" {
+					serveContent(w, r, false)
+					return
+				}
+				w.Header().Set(
+					"Content-Range",
+					fmt.Sprintf("bytes 0-%d/%d", len(testContent)-1, len(testContent)),
+				)
+				w.Header().Set("Content-Length", strconv.Itoa(len(testContent)))
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write(testContent)
+			},
+			wantRange: []string{"bytes=" + strconv.Itoa(len(testContent)/2) + "-", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var ranges []string
+			first := true
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				isFirst := first
+				if !first {
+					ranges = append(ranges, r.Header.Get("Range"))
+				}
+				first = false
+				mu.Unlock()
+				if isFirst {
+					serveHalf(w)
+				}
+				tt.second(w, r)
+			}))
+			defer server.Close()
+
+			// A single retry, which must resume correctly rather than fall
+			// back to a fresh download after a hash mismatch
+			d := testDownloader()
+			d.Retries = 1
+			res := d.Download(context.Background(), []DownloadTask{
+				{Url: server.URL + "/mod.jar", Dir: t.TempDir(), Hash: testHash(testContent)},
+			})[0]
+			checkResult(t, res)
+			mu.Lock()
+			defer mu.Unlock()
+			if fmt.Sprint(ranges) != fmt.Sprint(tt.wantRange) {
+				t.Errorf("got Range headers %q, want %q", ranges, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestDownloaderHashMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveContent(w, r, false)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	d := testDownloader()
+	d.Retries = 1
+	res := d.Download(context.Background(), []DownloadTask{
+		{
+			Url:        server.URL + "/mod.jar",
+			Dir:        dir,
+			Hash:       testHash([]byte("something else")),
+			HashMethod: types.HashSha512,
+		},
+	})[0]
+	if !errors.Is(res.Err, ErrorHashMismatch) {
+		t.Fatalf("got error %v, want %v", res.Err, ErrorHashMismatch)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("files left behind after a hash mismatch: %v", entries)
+	}
+}
+
+func TestDownloaderSameUrl(t *testing.T) {
+	// Both requests are held until the other arrived, so that the tasks are
+	// in flight at once
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived.Done()
+		arrived.Wait()
+		serveContent(w, r, false)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	url := server.URL + "/download"
+	d := testDownloader()
+	d.Retries = 0
+	results := d.Download(context.Background(), []DownloadTask{
+		{Url: url, Dir: dir, Filename: "a.jar", Hash: testHash(testContent)},
+		{Url: url, Dir: dir, Filename: "b.jar", Hash: testHash(testContent)},
+	})
+	for _, res := range results {
+		checkResult(t, res)
+	}
+	if results[0].Path == results[1].Path {
+		t.Errorf("both tasks were saved to %s", results[0].Path)
+	}
+}
+
+func TestDownloaderUrlWithoutFilename(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveContent(w, r, false)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	res := testDownloader().Download(context.Background(), []DownloadTask{
+		{Url: server.URL + "/files/", Dir: dir},
+	})[0]
+	checkResult(t, res)
+	if filepath.Dir(res.Path) != dir || filepath.Base(res.Path) == "" {
+		t.Errorf("saved to %s, want a named file in %s", res.Path, dir)
+	}
+}
+
+func TestDownloaderMirrors(t *testing.T) {
+	var originRequests, mirrorRequests atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originRequests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer origin.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorRequests.Add(1)
+		serveContent(w, r, false)
+	}))
+	defer mirror.Close()
+
+	originUrl, _ := url.Parse(origin.URL)
+	if err := SetMirror(originUrl.Host, mirror.URL); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = SetMirror(originUrl.Host, "") }()
+
+	fileUrl := origin.URL + "/mod.jar"
+	mirrors := MirrorsOf(fileUrl)
+	if want := mirror.URL + "/mod.jar"; len(mirrors) != 1 || mirrors[0] != want {
+		t.Fatalf("got mirrors %v, want [%s]", mirrors, want)
+	}
+	d := testDownloader()
+	d.Retries = 0
+	res := d.Download(context.Background(), []DownloadTask{
+		{Url: fileUrl, Mirrors: mirrors, Dir: t.TempDir(), Hash: testHash(testContent)},
+	})[0]
+	checkResult(t, res)
+	// The origin is raced as it is, rather than pointed to the mirror again
+	if originRequests.Load() != 1 || mirrorRequests.Load() != 1 {
+		t.Errorf(
+			"got %d requests to the origin and %d to the mirror, want 1 each",
+			originRequests.Load(), mirrorRequests.Load(),
+		)
+	}
+}
+
+func TestDownloaderUntrustedFilename(t *testing.T) {
+	tests := []struct {
+		name        string
+		disposition string
+		want        string
+	}{
+		{name: "traversal", disposition: `attachment; filename="../../.bashrc"`, want: ".bashrc"},
+		{name: "windows traversal", disposition: `attachment; filename="..\..\mod.jar"`, want: "mod.jar"},
+		{name: "parent only", disposition: `attachment; filename=".."`, want: "mod.jar"},
+		{name: "empty", disposition: `attachment; filename=""`, want: "mod.jar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Disposition", tt.disposition)
+				serveContent(w, r, false)
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+			res := testDownloader().Download(context.Background(), []DownloadTask{
+				{Url: server.URL + "/mod.jar", Dir: dir},
+			})[0]
+			checkResult(t, res)
+			if want := filepath.Join(dir, tt.want); res.Path != want {
+				t.Errorf("saved to %s, want %s", res.Path, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"lucy/global"
+	"lucy/logger"
+)
+
+// UserAgent identifies Lucy to the servers, as Modrinth asks clients to.
+var UserAgent = global.ProgramName + "/" + global.Version +
+	" (+https://github.com/minecraft-lucy/lucy)"
+
+// HttpOptions configures the shared client. Zero values fall back to the
+// defaults.
+type HttpOptions struct {
+	// ConnectTimeout limits dialing and the TLS handshake.
+	ConnectTimeout time.Duration
+
+	// ReadTimeout limits how long to wait for the response once the request
+	// is sent. It does not limit reading the body, so that large downloads
+	// are not cut off.
+	ReadTimeout time.Duration
+
+	// Proxy is used for all requests, the schemes http, https and socks5 are
+	// supported. When empty, HttpProxy and HttpsProxy are used depending on
+	// the scheme of the request, and then the environment.
+	Proxy      string
+	HttpProxy  string
+	HttpsProxy string
+
+	// CaFile is a PEM file of additional root certificates, e.g., of a
+	// corporate proxy.
+	CaFile             string
+	InsecureSkipVerify bool
+}
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultReadTimeout    = 30 * time.Second
+)
+
+var (
+	client   = newClient(HttpOptions{}, nil)
+	clientMu sync.RWMutex
+)
+
+// proxyFallback supplies proxies when none is configured in HttpOptions. It is
+// set by packages that util cannot import, e.g., to read the MCDR config.
+var proxyFallback func() (httpProxy string, httpsProxy string)
+
+// Client returns the shared client. Every outgoing request should use it.
+// Sources are handed it as a Requester instead, see NewRequester.
+func Client() *http.Client {
+	clientMu.RLock()
+	defer clientMu.RUnlock()
+	return client
+}
+
+// ConfigureHttp replaces the shared client with one built from the options,
+// and returns it to be handed to the sources.
+func ConfigureHttp(options HttpOptions) (*http.Client, error) {
+	var roots *x509.CertPool
+	if options.CaFile != "" {
+		pem, err := os.ReadFile(options.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca file: %w", err)
+		}
+		roots, err = x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", options.CaFile)
+		}
+	}
+	for _, p := range []string{options.Proxy, options.HttpProxy, options.HttpsProxy} {
+		if _, err := parseProxy(p); err != nil {
+			return nil, err
+		}
+	}
+
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	client = newClient(options, roots)
+	return client, nil
+}
+
+// SetProxyFallback registers where to look for proxies when none is set in
+// HttpOptions. It is consulted once, on the first request.
+func SetProxyFallback(f func() (httpProxy string, httpsProxy string)) {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	proxyFallback = f
+}
+
+func newClient(options HttpOptions, roots *x509.CertPool) *http.Client {
+	connectTimeout := options.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	readTimeout := options.ReadTimeout
+	if readTimeout <= 0 {
+		readTimeout = defaultReadTimeout
+	}
+
+	// The timeouts only cover connecting and waiting for the response, a
+	// pooled connection is not cut off for staying idle between requests
+	dialer := &net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:       proxyFunc(options),
+		DialContext: dialer.DialContext,
+		TLSClientConfig: &tls.Config{
+			RootCAs:            roots,
+			InsecureSkipVerify: options.InsecureSkipVerify,
+		},
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConnsPerHost:   8,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	if options.InsecureSkipVerify {
+		logger.Warn(errors.New("tls verification is disabled"))
+	}
+	return &http.Client{Transport: transport, CheckRedirect: checkRedirect}
+}
+
+// maxRedirects is the limit of http.Client without CheckRedirect.
+const maxRedirects = 10
+
+// checkRedirect points redirects to mirrors as Do does for the first request.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	rewriteRequest(req)
+	return nil
+}
+
+// proxyFunc picks the proxy of a request: the explicit proxy, then the proxy
+// for its scheme, then the fallback, and finally the environment.
+func proxyFunc(options HttpOptions) func(*http.Request) (*url.URL, error) {
+	var once sync.Once
+	httpProxy, httpsProxy := options.HttpProxy, options.HttpsProxy
+	return func(req *http.Request) (*url.URL, error) {
+		if options.Proxy != "" {
+			return parseProxy(options.Proxy)
+		}
+		once.Do(func() {
+			clientMu.RLock()
+			fallback := proxyFallback
+			clientMu.RUnlock()
+			if fallback != nil && httpProxy == "" && httpsProxy == "" {
+				httpProxy, httpsProxy = fallback()
+			}
+		})
+		switch {
+		case req.URL.Scheme == "https" && httpsProxy != "":
+			return parseProxy(httpsProxy)
+		case req.URL.Scheme == "http" && httpProxy != "":
+			return parseProxy(httpProxy)
+		default:
+			return http.ProxyFromEnvironment(req)
+		}
+	}
+}
+
+func parseProxy(proxy string) (*url.URL, error) {
+	if proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %s: %w", proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", proxy)
+	}
+}
+
+// Get is a replacement of http.Get. All outgoing requests should go through
+// this package, so that the shared client and mirrors are applied to them.
+func Get(url string) (*http.Response, error) {
+	return get(context.Background(), Client(), url)
+}
+
+func get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Do(client, req)
+}
+
+// Requester sends the requests of a source. Sources are handed one, see
+// SetRequester of each, rather than reaching for the shared client, so that
+// they can be given a differently configured client.
+type Requester interface {
+	// Get is util.Get, cancelled along with ctx.
+	Get(ctx context.Context, url string) (*http.Response, error)
+	// GetCached is util.GetCached, cancelled along with ctx.
+	GetCached(ctx context.Context, url string) (data []byte, status int, err error)
+}
+
+// NewRequester returns the Requester sending through the client, with the
+// mirrors, tokens and rate limits of Do, and the network cache of GetCached.
+func NewRequester(client *http.Client) Requester {
+	return requester{client: client}
+}
+
+type requester struct {
+	client *http.Client
+}
+
+func (r requester) Get(ctx context.Context, url string) (*http.Response, error) {
+	return get(ctx, r.client, url)
+}
+
+func (r requester) GetCached(ctx context.Context, url string) (data []byte, status int, err error) {
+	data, _, status, _, err = fetchCached(ctx, r.client, url, 0)
+	return data, status, err
+}
+
+// Do sends the request with the client, after pointing it to a mirror if one
+// is configured for its host, and authorizing it if a token is set for the
+// host it ends up at. Requests are held within the rate limit of the host, and
+// a response rejecting them for the limit becomes a RateLimitError.
+func Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if Offline() {
+		return nil, fmt.Errorf("%w: %s", ErrorOffline, req.URL)
+	}
+	rewriteRequest(req)
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent)
+	}
+	authorize(req)
+	return doLimited(client, req)
+}
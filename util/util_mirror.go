@@ -0,0 +1,123 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"lucy/logger"
+)
+
+// mirrors maps a host to the base that replaces the scheme and the host of a
+// url. A base with a path is a prefix, which also suits proxies that take the
+// original url as their path.
+//
+// They are set from the mirrors key of the config, e.g.,
+//
+//	LUCY_MIRRORS="api.modrinth.com=https://mirror.example.com,raw.githubusercontent.com=https://ghproxy.example.com/https://raw.githubusercontent.com"
+var (
+	mirrors   = map[string]*url.URL{}
+	mirrorsMu sync.RWMutex
+)
+
+// SetMirror routes all requests to host through base. An empty base removes
+// the mirror.
+func SetMirror(host string, base string) error {
+	mirrorsMu.Lock()
+	defer mirrorsMu.Unlock()
+	host = strings.ToLower(host)
+	if base == "" {
+		delete(mirrors, host)
+		return nil
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid mirror for %s: %s", host, base)
+	}
+	mirrors[host] = u
+	return nil
+}
+
+// RewriteUrl returns the url with its scheme and host replaced by the mirror
+// of the host, and its path appended to the path of the mirror. It is the url
+// itself if there is no mirror.
+func RewriteUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	if rewritten, ok := rewriteUrl(u); ok {
+		return rewritten.String()
+	}
+	return rawUrl
+}
+
+// rewriteUrl implements RewriteUrl. A mirror set for the host with its port
+// takes precedence over one for the host alone. The user info of the original
+// url is dropped, as it is meant for the original host only.
+func rewriteUrl(u *url.URL) (*url.URL, bool) {
+	mirrorsMu.RLock()
+	base, ok := mirrors[strings.ToLower(u.Host)]
+	if !ok {
+		base, ok = mirrors[strings.ToLower(u.Hostname())]
+	}
+	mirrorsMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	escaped := strings.TrimSuffix(base.EscapedPath(), "/") + u.EscapedPath()
+	unescaped, err := url.PathUnescape(escaped)
+	if err != nil {
+		return nil, false
+	}
+	return &url.URL{
+		Scheme:   base.Scheme,
+		User:     base.User,
+		Host:     base.Host,
+		Path:     unescaped,
+		RawPath:  escaped,
+		RawQuery: u.RawQuery,
+		Fragment: u.Fragment,
+	}, true
+}
+
+// MirrorsOf returns the urls hosting the same file as rawUrl besides itself,
+// i.e., the url on the mirror of its host. Downloads race them against the
+// original, so that the mirror is used where it is faster, and the original
+// remains when the mirror is down.
+func MirrorsOf(rawUrl string) []string {
+	if rewritten := RewriteUrl(rawUrl); rewritten != rawUrl {
+		return []string{rewritten}
+	}
+	return nil
+}
+
+type withoutMirrorsKey struct{}
+
+// withoutMirrors makes the requests under ctx go to their urls as they are,
+// for urls already resolved by MirrorsOf.
+func withoutMirrors(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutMirrorsKey{}, true)
+}
+
+// rewriteRequest points the request to the mirror of its host. The shared
+// client calls it on redirects as well, so that a redirect to a mirrored host
+// goes to the mirror too.
+func rewriteRequest(req *http.Request) {
+	original := req.URL.String()
+	if req.Context().Value(withoutMirrorsKey{}) != nil {
+		logger.Debug("request " + original)
+		return
+	}
+	u, ok := rewriteUrl(req.URL)
+	if !ok {
+		logger.Debug("request " + original)
+		return
+	}
+	req.URL = u
+	req.Host = u.Host
+	logger.Debug("request " + original + " rewritten to " + u.String())
+}
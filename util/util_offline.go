@@ -0,0 +1,22 @@
+package util
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var ErrorOffline = errors.New("not available offline")
+
+var offline atomic.Bool
+
+// SetOffline turns the offline mode on or off. While offline, Do fails every
+// request at once with ErrorOffline, and GetCached serves cached responses
+// however stale they are. Metadata and files are then only available if they
+// were fetched before, e.g., by `lucy prefetch`.
+func SetOffline(on bool) {
+	offline.Store(on)
+}
+
+func Offline() bool {
+	return offline.Load()
+}
@@ -0,0 +1,207 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"lucy/logger"
+	"lucy/tools"
+)
+
+var ErrorRateLimited = errors.New("rate limited")
+
+// RateLimitError is returned instead of a 403 or 429 response when the API
+// limit of a host is spent.
+type RateLimitError struct {
+	Host  string
+	Reset time.Time // zero if the host did not say
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return fmt.Sprintf("%s: %s", ErrorRateLimited, e.Host)
+	}
+	return fmt.Sprintf(
+		"%s: %s, resets at %s (in %s)",
+		ErrorRateLimited,
+		e.Host,
+		e.Reset.Local().Format(time.TimeOnly),
+		time.Until(e.Reset).Round(time.Second),
+	)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrorRateLimited
+}
+
+const (
+	// maxRateLimitWait is how long a request may be held back for a limit to
+	// reset. Longer waits, e.g., for the hourly limit of GitHub, fail instead,
+	// since the user is better off knowing than watching a frozen screen.
+	maxRateLimitWait = 30 * time.Second
+
+	// maxRateLimitRetries bounds the retries of a rate limited request.
+	maxRateLimitRetries = 3
+)
+
+// rateLimit tracks the budget of a host from the X-RateLimit-* headers of its
+// responses. The remaining count is also decreased locally for every request
+// sent, so that parallel requests, e.g., while enriching search hits, queue up
+// instead of all running into the limit.
+type rateLimit struct {
+	mu        sync.Mutex
+	remaining int // -1 if unknown
+	reset     time.Time
+}
+
+var rateLimits sync.Map // host to *rateLimit
+
+func rateLimitOf(host string) *rateLimit {
+	l, _ := rateLimits.LoadOrStore(host, &rateLimit{remaining: -1})
+	return l.(*rateLimit)
+}
+
+// reserve takes a request from the budget. When the budget is spent, it waits
+// for the reset if that is near, and fails otherwise.
+func (l *rateLimit) reserve(ctx context.Context, host string) error {
+	for {
+		l.mu.Lock()
+		if l.remaining != 0 || !time.Now().Before(l.reset) {
+			if l.remaining > 0 {
+				l.remaining--
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		reset := l.reset
+		l.mu.Unlock()
+
+		wait := time.Until(reset)
+		if wait > maxRateLimitWait {
+			return &RateLimitError{Host: host, Reset: reset}
+		}
+		logger.Info(fmt.Sprintf("rate limit of %s spent, waiting %s", host, wait.Round(time.Second)))
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// update records the budget announced in the response. It reports whether the
+// response is a rejection for exceeding the limit, and when to try again.
+func (l *rateLimit) update(resp *http.Response) (limited bool, reset time.Time) {
+	now := time.Now()
+	header := resp.Header
+	remaining, remainingErr := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset = parseRateLimitReset(header.Get("X-RateLimit-Reset"), now)
+	if after, ok := parseRetryAfter(header.Get("Retry-After"), now); ok {
+		remaining, remainingErr = 0, nil
+		reset = after
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		limited = true
+	case http.StatusForbidden:
+		// GitHub answers 403 both for a spent limit and for lack of access
+		limited = remainingErr == nil && remaining == 0
+	}
+	if limited {
+		remaining, remainingErr = 0, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if remainingErr == nil {
+		l.remaining = remaining
+		l.reset = reset
+	}
+	return limited, reset
+}
+
+// parseRateLimitReset reads X-RateLimit-Reset, which GitHub sends as a Unix
+// time and Modrinth as seconds from now.
+func parseRateLimitReset(value string, now time.Time) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}
+	}
+	if n > 1_000_000_000 {
+		return time.Unix(n, 0)
+	}
+	return now.Add(time.Duration(n) * time.Second)
+}
+
+// parseRetryAfter reads Retry-After, either in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// doLimited sends the request within the rate limit of its host. A rejection
+// for exceeding the limit is retried if the limit resets soon, and otherwise
+// turned into a RateLimitError.
+func doLimited(client *http.Client, req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	limit := rateLimitOf(host)
+	// Only requests without a body, or with a way to re-read it, can be sent
+	// again
+	replayable := req.Body == nil || req.GetBody != nil
+	for attempt := 0; ; attempt++ {
+		if err := limit.reserve(req.Context(), host); err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		limited, reset := limit.update(resp)
+		if !limited {
+			return resp, nil
+		}
+		tools.CloseReader(resp.Body, logger.Warn)
+
+		wait := time.Until(reset)
+		if reset.IsZero() {
+			wait = time.Second << attempt
+		}
+		if !replayable || attempt >= maxRateLimitRetries || wait > maxRateLimitWait {
+			return nil, &RateLimitError{Host: host, Reset: reset}
+		}
+		logger.Info(fmt.Sprintf("rate limited by %s, retrying in %s", host, wait.Round(time.Second)))
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}